@@ -1,58 +1,129 @@
 package main
 
 import (
-	"fmt"
-	"log"
+	"context"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+
 	"github.com/saedabdu/stockticker/internal/api/handler"
 	"github.com/saedabdu/stockticker/internal/cache"
 	"github.com/saedabdu/stockticker/internal/client"
 	"github.com/saedabdu/stockticker/internal/config"
+	"github.com/saedabdu/stockticker/internal/requestid"
 	"github.com/saedabdu/stockticker/internal/service"
+	"github.com/saedabdu/stockticker/internal/tracing"
+	"github.com/saedabdu/stockticker/internal/version"
+)
+
+const (
+	// shutdownTimeout is how long in-flight requests are given to complete on shutdown
+	shutdownTimeout = 15 * time.Second
+	// cacheCleanupInterval is how often expired cache entries are removed
+	cacheCleanupInterval = 5 * time.Minute
+	// rateLimiterCleanupInterval is how often idle clients are removed from the rate limiter
+	rateLimiterCleanupInterval = time.Minute
 )
 
 func main() {
+	// Log with defaults until the configured level is known
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
 	// Load configuration
 	cfg, err := config.New()
 	if err != nil {
-		log.Fatalf("Error loading configuration: %v", err)
+		logger.Error("error loading configuration", "error", err)
+		os.Exit(1)
 	}
 
-	// Create API client
-	apiClient := client.NewAlphaVantage(cfg.APIKey)
+	// Records logged with a request context are tagged with its request ID
+	logger = slog.New(requestid.NewLogHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: cfg.LogLevel})))
+	logger.Info("stockticker build", "version", version.Version, "commit", version.Commit, "build_time", version.BuildTime)
+
+	// Set up tracing, a no-op unless OTEL_EXPORTER is set
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingExporter)
+	if err != nil {
+		logger.Error("error setting up tracing", "exporter", cfg.TracingExporter, "error", err)
+		os.Exit(1)
+	}
+
+	// Create API client for the configured provider
+	clientOpts := []client.Option{
+		client.WithRetry(cfg.MaxRetries, cfg.RetryBaseDelay),
+		client.WithTimeout(cfg.UpstreamTimeout),
+		client.WithRateLimit(cfg.RequestsPerMinute),
+		client.WithUserAgent(cfg.UserAgent),
+		client.WithMaxResponseSize(cfg.MaxResponseSize),
+	}
+	var apiClient client.StockProvider
+	switch cfg.Provider {
+	case config.ProviderFinnhub:
+		apiClient = client.NewFinnhub(cfg.APIKey, clientOpts...)
+	case config.ProviderMock:
+		apiClient = client.NewMock()
+	default:
+		apiClient = client.NewAlphaVantage(cfg.APIKey, clientOpts...)
+	}
 
 	// Create cache
-	cacheInstance := cache.New()
+	cacheStore, closeCache, err := newCache(cfg, logger)
+	if err != nil {
+		logger.Error("error creating cache", "backend", cfg.CacheBackend, "error", err)
+		os.Exit(1)
+	}
 
 	// Create service
-	stockService := service.New(cfg, apiClient, cacheInstance)
+	stockService := service.New(cfg, apiClient, cacheStore, logger)
+
+	// Warm the cache in the background so startup is not held up by the provider
+	prefetchCtx, cancelPrefetch := context.WithCancel(context.Background())
+	defer cancelPrefetch()
+	if cfg.Prefetch {
+		go stockService.Prefetch(prefetchCtx)
+	}
+
+	// Keep recently requested data fresh, a no-op unless REFRESH_AHEAD_WINDOW is set
+	stockService.StartRefresher()
 
 	// Create handler
-	stockHandler := handler.NewStockHandler(stockService)
+	stockHandler := handler.NewStockHandler(cfg, stockService, logger)
 
-	// Setup routes
-	http.HandleFunc("/stocks", stockHandler.HandleStocks)
-	http.HandleFunc("/health", stockHandler.HandleHealth)
+	// Create per-client rate limiter
+	var rateLimiter *handler.RateLimiter
+	if cfg.ClientRequestsPerMinute > 0 {
+		rateLimiter = handler.NewRateLimiter(cfg.ClientRequestsPerMinute, cfg.TrustedProxies)
+		rateLimiter.StartJanitor(rateLimiterCleanupInterval)
+	}
 
 	// Start HTTP server
 	server := &http.Server{
-		Addr:         fmt.Sprintf(":%s", cfg.Port),
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 10 * time.Second,
-		IdleTimeout:  120 * time.Second,
+		Addr:         net.JoinHostPort(cfg.BindAddress, cfg.Port),
+		Handler:      newHandler(cfg, stockHandler, rateLimiter, logger),
+		ReadTimeout:  cfg.ServerReadTimeout,
+		WriteTimeout: cfg.ServerWriteTimeout,
+		IdleTimeout:  cfg.ServerIdleTimeout,
 	}
 
-	// Start server in a goroutine
+	// Start server in a goroutine, serving HTTPS with HTTP/2 when a certificate is configured
 	go func() {
-		log.Printf("Starting server on port %s", cfg.Port)
-		log.Printf("Configuration: Symbol=%s, NDays=%d", cfg.Symbol, cfg.NDays)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
+		useTLS := cfg.TLSCertFile != ""
+		logger.Info("starting server", "address", server.Addr, "tls", useTLS, "provider", cfg.Provider, "symbol", cfg.Symbol, "days", cfg.NDays)
+		var err error
+		if useTLS {
+			err = server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error("server error", "error", err)
+			os.Exit(1)
 		}
 	}()
 
@@ -61,5 +132,112 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down server...")
+	logger.Info("shutting down server")
+	cancelPrefetch()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err = server.Shutdown(ctx)
+	stockService.StopRefresher()
+	closeCache()
+	if rateLimiter != nil {
+		rateLimiter.Stop()
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("error flushing traces", "error", err)
+	}
+	if err != nil {
+		logger.Error("server shutdown error", "error", err)
+		cancel()
+		os.Exit(1)
+	}
+
+	logger.Info("server stopped")
+}
+
+// newHandler wraps the router with the middleware applied to every request
+func newHandler(cfg *config.Config, stockHandler *handler.StockHandler, rateLimiter *handler.RateLimiter, logger *slog.Logger) http.Handler {
+	h := handler.RequestID(handler.CORS(cfg.AllowedOrigins)(handler.Gzip(newRouter(cfg, stockHandler, rateLimiter, logger))))
+
+	// Trace every request, continuing any trace context sent by the caller
+	return otelhttp.NewHandler(h, "stockticker",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}))
+}
+
+// newRouter registers the service's routes. Paths matching no route get a
+// JSON 404 rather than the default plaintext one.
+func newRouter(cfg *config.Config, stockHandler *handler.StockHandler, rateLimiter *handler.RateLimiter, logger *slog.Logger) *http.ServeMux {
+	// Data and admin endpoints are instrumented, rate limited per client and
+	// require AUTH_TOKEN when it is set
+	data := func(h http.HandlerFunc) http.Handler {
+		var next http.Handler = handler.Auth(cfg.AuthToken)(h)
+		if rateLimiter != nil {
+			next = rateLimiter.Limit(next)
+		}
+		return handler.Instrument(logger)(next)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/stocks", data(stockHandler.HandleStocks))
+	mux.Handle("/stocks/latest", data(stockHandler.HandleLatest))
+	mux.Handle("/stocks/average", data(stockHandler.HandleAverage))
+	mux.Handle("/stocks/alert", data(stockHandler.HandleAlert))
+	mux.Handle("/stocks/extremes", data(stockHandler.HandleExtremes))
+	mux.Handle("/stocks/correlation", data(stockHandler.HandleCorrelation))
+	mux.Handle("/stocks/stream", data(stockHandler.HandleStream))
+	mux.Handle("/ws", data(stockHandler.HandleWebSocket))
+	mux.Handle("/quote", data(stockHandler.HandleQuote))
+	mux.Handle("/search", data(stockHandler.HandleSearch))
+	mux.Handle("/prefetch", data(stockHandler.HandlePrefetch))
+	mux.HandleFunc("/health", stockHandler.HandleHealth)
+	mux.HandleFunc("/version", stockHandler.HandleVersion)
+	mux.HandleFunc("/cache/stats", stockHandler.HandleCacheStats)
+	mux.HandleFunc("/status", stockHandler.HandleStatus)
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/", stockHandler.HandleNotFound)
+	return mux
+}
+
+// newCache creates the configured cache backend along with a function that
+// releases it on shutdown
+func newCache(cfg *config.Config, logger *slog.Logger) (cache.Store, func(), error) {
+	if cfg.CacheBackend == config.CacheBackendRedis {
+		redisCache, err := cache.NewRedis(cfg.RedisURL, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := redisCache.Ping(ctx); err != nil {
+			// Requests fall back to the provider until Redis becomes reachable
+			logger.Warn("error connecting to Redis", "error", err)
+		}
+
+		return cache.WithJitter(redisCache, cfg.CacheTTLJitter), func() {
+			if err := redisCache.Close(); err != nil {
+				logger.Error("error closing Redis connection", "error", err)
+			}
+		}, nil
+	}
+
+	memoryCache := cache.New(cfg.CacheMaxItems)
+	if cfg.CacheFile != "" {
+		if err := memoryCache.LoadFile(cfg.CacheFile); err != nil {
+			logger.Warn("error loading cache file", "path", cfg.CacheFile, "error", err)
+		}
+	}
+	memoryCache.StartJanitor(cacheCleanupInterval)
+
+	return cache.WithJitter(memoryCache, cfg.CacheTTLJitter), func() {
+		memoryCache.Stop()
+		if cfg.CacheFile != "" {
+			if err := memoryCache.SaveFile(cfg.CacheFile); err != nil {
+				logger.Error("error saving cache file", "path", cfg.CacheFile, "error", err)
+			}
+		}
+	}, nil
 }
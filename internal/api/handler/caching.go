@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+// sendConditionalJSONResponse sends data as JSON with an ETag derived from the
+// serialized body. When the request's If-None-Match matches it, 304 Not Modified
+// is sent without a body instead.
+func (h *StockHandler) sendConditionalJSONResponse(w http.ResponseWriter, r *http.Request, data interface{}) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(data); err != nil {
+		h.logger.Error("error encoding JSON response", "error", err)
+		h.sendErrorResponse(w, "error encoding response", http.StatusInternalServerError)
+		return
+	}
+
+	etag := computeETag(body.Bytes())
+	w.Header().Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body.Bytes()); err != nil {
+		h.logger.Error("error writing JSON response", "error", err)
+	}
+}
+
+// computeETag returns a strong entity tag for body
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag.
+// Weak validators match their strong equivalent, as required for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// setCacheControl allows clients and shared caches to reuse a response for as
+// long as the data it was built from remains in the service's cache. Responses
+// are marked private when authentication is enabled.
+func (h *StockHandler) setCacheControl(w http.ResponseWriter, cachedAt time.Time) {
+	if cachedAt.IsZero() {
+		return
+	}
+
+	remaining := h.config.CacheTTL - time.Since(cachedAt)
+	maxAge := int(math.Max(0, math.Ceil(remaining.Seconds())))
+
+	value := "max-age=" + strconv.Itoa(maxAge)
+	if h.config.AuthToken != "" {
+		value = "private, " + value
+	}
+	w.Header().Set("Cache-Control", value)
+}
+
+// setStaleHeader marks a response built from expired cached data that was
+// served because fetching fresh data failed
+func setStaleHeader(w http.ResponseWriter, stockData *models.StockData) {
+	if stockData.ServedStale {
+		w.Header().Set("X-Cache", "STALE")
+	}
+}
+
+// oldestCachedAt returns the earliest non-zero time, which bounds the freshness
+// of a response built from several cached results
+func oldestCachedAt(times ...time.Time) time.Time {
+	var oldest time.Time
+	for _, t := range times {
+		if !t.IsZero() && (oldest.IsZero() || t.Before(oldest)) {
+			oldest = t
+		}
+	}
+	return oldest
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/api"
+	"github.com/saedabdu/stockticker/internal/config"
+)
+
+func TestSendConditionalJSONResponse(t *testing.T) {
+	h := NewStockHandler(&config.Config{}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	data := api.LatestResponse{Symbol: "AAPL", Date: "2023-01-03", Close: 125.07}
+
+	first := httptest.NewRecorder()
+	h.sendConditionalJSONResponse(first, httptest.NewRequest(http.MethodGet, "/stocks/latest", nil), data)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected 200 with an ETag, got %d %q", first.Code, etag)
+	}
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{name: "matching", ifNoneMatch: etag, wantStatus: http.StatusNotModified},
+		{name: "weak matching", ifNoneMatch: "W/" + etag, wantStatus: http.StatusNotModified},
+		{name: "in list", ifNoneMatch: `"other", ` + etag, wantStatus: http.StatusNotModified},
+		{name: "wildcard", ifNoneMatch: "*", wantStatus: http.StatusNotModified},
+		{name: "stale", ifNoneMatch: `"other"`, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/stocks/latest", nil)
+			req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			rec := httptest.NewRecorder()
+
+			h.sendConditionalJSONResponse(rec, req, data)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if rec.Header().Get("ETag") != etag {
+				t.Errorf("expected the same ETag %s, got %s", etag, rec.Header().Get("ETag"))
+			}
+			if tt.wantStatus == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Errorf("expected an empty body, got %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSetCacheControl(t *testing.T) {
+	tests := []struct {
+		name      string
+		authToken string
+		cachedAt  time.Time
+		want      string
+	}{
+		{name: "fresh", cachedAt: time.Now().Add(-5 * time.Minute), want: "max-age=600"},
+		{name: "expired", cachedAt: time.Now().Add(-20 * time.Minute), want: "max-age=0"},
+		{name: "authenticated", authToken: "secret", cachedAt: time.Now().Add(-5 * time.Minute), want: "private, max-age=600"},
+		{name: "unknown age", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewStockHandler(&config.Config{CacheConfig: config.CacheConfig{CacheTTL: 15 * time.Minute}, ServerConfig: config.ServerConfig{AuthToken: tt.authToken}}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			rec := httptest.NewRecorder()
+
+			h.setCacheControl(rec, tt.cachedAt)
+
+			if got := rec.Header().Get("Cache-Control"); got != tt.want {
+				t.Errorf("expected Cache-Control %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
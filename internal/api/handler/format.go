@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+// wantsCSV reports whether the client asked for CSV via the format parameter or Accept header
+func wantsCSV(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.EqualFold(format, "csv")
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/csv")
+}
+
+// wantsXML reports whether the client asked for XML via the format parameter or Accept header
+func wantsXML(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.EqualFold(format, "xml")
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/xml") || strings.Contains(accept, "text/xml")
+}
+
+// parseFields returns the names listed in the fields query parameter, or nil
+// when the response should not be filtered
+func parseFields(r *http.Request) []string {
+	var fields []string
+	for _, field := range strings.Split(r.URL.Query().Get("fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// selectFields returns the JSON object of data reduced to the named top-level
+// fields. Names that data does not have are ignored.
+func selectFields(data interface{}, fields []string) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &object); err != nil {
+		return nil, err
+	}
+
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := object[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected, nil
+}
+
+// sendXMLResponse writes data as an XML document
+func (h *StockHandler) sendXMLResponse(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return
+	}
+	if err := xml.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("error encoding XML response", "error", err)
+	}
+}
+
+// sendCSVResponse writes the stock prices as CSV with a date,close header row,
+// or the selected price field in place of close
+func (h *StockHandler) sendCSVResponse(w http.ResponseWriter, stockData *models.StockData) {
+	field := stockData.PriceField
+	if field == "" {
+		field = models.PriceFieldClose
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", stockData.Symbol+".csv"))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	records := make([][]string, 0, len(stockData.Prices)+1)
+	records = append(records, []string{"date", string(field)})
+	for _, price := range stockData.Prices {
+		records = append(records, []string{price.Date, strconv.FormatFloat(price.Value(field), 'f', -1, 64)})
+	}
+
+	if err := writer.WriteAll(records); err != nil {
+		h.logger.Error("error encoding CSV response", "symbol", stockData.Symbol, "error", err)
+	}
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/config"
+)
+
+func TestHandleStocksXML(t *testing.T) {
+	h := newTestHandler(&config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}})
+
+	requests := map[string]*http.Request{
+		"format parameter": httptest.NewRequest(http.MethodGet, "/stocks?symbol=AAPL&format=xml&sma=1", nil),
+		"accept header":    httptest.NewRequest(http.MethodGet, "/stocks?symbol=AAPL&sma=1", nil),
+	}
+	requests["accept header"].Header.Set("Accept", "application/xml")
+
+	for name, req := range requests {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.HandleStocks(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Content-Type"); got != "application/xml" {
+				t.Errorf("expected Content-Type application/xml, got %q", got)
+			}
+
+			body := rec.Body.String()
+			if !strings.HasPrefix(body, xml.Header) {
+				t.Errorf("expected an XML declaration, got %q", body)
+			}
+
+			var doc struct {
+				XMLName xml.Name `xml:"stock"`
+				Symbol  string   `xml:"symbol"`
+				Average float64  `xml:"average"`
+				Prices  []struct {
+					Date  string  `xml:"date"`
+					Close float64 `xml:"close"`
+				} `xml:"prices>price"`
+				SMA []struct {
+					Date  string  `xml:"date"`
+					Value float64 `xml:"value"`
+				} `xml:"sma>point"`
+				CachedAt string `xml:"cached_at"`
+			}
+			if err := xml.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("invalid XML: %v\n%s", err, body)
+			}
+
+			if doc.Symbol != "AAPL" || doc.Average != 1 {
+				t.Errorf("expected symbol AAPL with average 1, got %q and %v", doc.Symbol, doc.Average)
+			}
+			if len(doc.Prices) != 1 || doc.Prices[0].Date != "2023-01-03" || doc.Prices[0].Close != 1 {
+				t.Errorf("expected one price element for 2023-01-03, got %+v", doc.Prices)
+			}
+			if len(doc.SMA) != 1 || doc.SMA[0].Value != 1 {
+				t.Errorf("expected one sma point, got %+v", doc.SMA)
+			}
+			if doc.CachedAt == "" {
+				t.Error("expected a cached_at element")
+			}
+			if strings.Contains(body, "<vwap>") || strings.Contains(body, "<page>") {
+				t.Errorf("expected unset optional elements to be omitted, got %s", body)
+			}
+		})
+	}
+}
+
+func TestWantsXMLDefaultsToJSON(t *testing.T) {
+	if wantsXML(httptest.NewRequest(http.MethodGet, "/stocks", nil)) {
+		t.Error("expected JSON when neither format nor Accept asks for XML")
+	}
+	req := httptest.NewRequest(http.MethodGet, "/stocks?format=json", nil)
+	req.Header.Set("Accept", "application/xml")
+	if wantsXML(req) {
+		t.Error("expected the format parameter to take precedence over Accept")
+	}
+}
+
+func TestHandleStocksFields(t *testing.T) {
+	h := newTestHandler(&config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}})
+
+	rec := httptest.NewRecorder()
+	h.HandleStocks(rec, httptest.NewRequest(http.MethodGet, "/stocks?symbol=AAPL&fields=symbol,%20average,unknown", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	// Unknown names are ignored
+	if len(got) != 2 || got["symbol"] != "AAPL" || got["average"] != 1.0 {
+		t.Errorf("expected only symbol AAPL and average 1, got %v", got)
+	}
+}
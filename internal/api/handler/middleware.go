@@ -0,0 +1,367 @@
+package handler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/api"
+	"github.com/saedabdu/stockticker/internal/config"
+	"github.com/saedabdu/stockticker/internal/metrics"
+	"github.com/saedabdu/stockticker/internal/requestid"
+)
+
+const (
+	// gzipMinSize is the smallest response body worth compressing
+	gzipMinSize = 1024
+
+	// CORS preflight response values
+	corsAllowMethods = "GET, POST, DELETE, OPTIONS"
+	corsAllowHeaders = "Content-Type, Authorization"
+
+	// maxRequestIDLength is the longest client-supplied request ID that is accepted
+	maxRequestIDLength = 128
+)
+
+// RequestID tags every request with the ID given in its X-Request-ID header,
+// generating one when the header is missing or unusable. The ID is stored in
+// the request context for logging and echoed in the X-Request-ID response header.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !validRequestID(id) {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+	})
+}
+
+// validRequestID reports whether a client-supplied request ID is short and
+// printable enough to be trusted in headers and log lines
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// Auth returns middleware that requires requests to present token as an
+// "Authorization: Bearer" header, answering others with 401. When token is
+// empty authentication is disabled and requests pass through unchanged.
+func Auth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if token == "" {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeErrorResponse(w, "missing or invalid bearer token", http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeErrorResponse sends an error response from middleware, which has no handler to log through
+func writeErrorResponse(w http.ResponseWriter, message string, statusCode int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(api.ErrorResponse{Code: codeForStatus(statusCode), Error: message})
+}
+
+// CORS returns middleware that allows cross-origin requests from allowedOrigins.
+// An entry of "*" allows any origin. When allowedOrigins is empty no CORS headers are set.
+// Preflight OPTIONS requests from allowed origins are answered with 204.
+func CORS(allowedOrigins []string) func(http.Handler) http.Handler {
+	allowAll := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[origin] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" || (!allowAll && !allowed[origin]) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if allowAll {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Add("Vary", "Origin")
+			}
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", corsAllowMethods)
+				w.Header().Set("Access-Control-Allow-Headers", corsAllowHeaders)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Gzip compresses responses for clients that advertise gzip support in Accept-Encoding.
+// Bodies smaller than gzipMinSize are sent uncompressed.
+func Gzip(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		// Upgraded connections take over the raw connection and are never compressed here
+		if !acceptsGzip(r.Header.Values("Accept-Encoding")) || r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		defer gw.Close()
+
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether the Accept-Encoding header values allow a gzip
+// response. A coding with q=0 is refused, and an explicit gzip entry takes
+// precedence over the * wildcard.
+func acceptsGzip(values []string) bool {
+	var gzipListed, gzipAllowed, wildcardAllowed bool
+	for _, value := range values {
+		for _, coding := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name != "gzip" && name != "*" {
+				continue
+			}
+
+			allowed := true
+			for _, param := range strings.Split(params, ";") {
+				key, q, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(key), "q") {
+					continue
+				}
+				weight, err := strconv.ParseFloat(strings.TrimSpace(q), 64)
+				allowed = err == nil && weight > 0
+			}
+
+			if name == "gzip" {
+				gzipListed, gzipAllowed = true, allowed
+			} else {
+				wildcardAllowed = allowed
+			}
+		}
+	}
+
+	if gzipListed {
+		return gzipAllowed
+	}
+	return wildcardAllowed
+}
+
+// gzipResponseWriter buffers the start of a response and only compresses it once
+// the body reaches gzipMinSize
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	buf         []byte
+	gz          *gzip.Writer
+	passthrough bool
+}
+
+// WriteHeader records the status code; it is sent once the encoding has been decided
+func (w *gzipResponseWriter) WriteHeader(statusCode int) {
+	w.statusCode = statusCode
+}
+
+// Write buffers p until enough data has been written to decide whether to compress
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= gzipMinSize {
+		if err := w.startGzip(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Flush sends any buffered data to the client. If compression has not started,
+// the response continues uncompressed so streamed data is not held back.
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	} else if !w.passthrough {
+		w.startPassthrough()
+	}
+
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close flushes the remaining response, compressing it if compression has started
+func (w *gzipResponseWriter) Close() error {
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	if !w.passthrough {
+		return w.startPassthrough()
+	}
+	return nil
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// startGzip sends the headers for a compressed response and writes the buffered body through gzip
+func (w *gzipResponseWriter) startGzip() error {
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Encoding", "gzip")
+	w.ResponseWriter.WriteHeader(w.statusCode)
+
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	_, err := w.gz.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// startPassthrough sends the headers and buffered body uncompressed
+func (w *gzipResponseWriter) startPassthrough() error {
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.statusCode)
+
+	_, err := w.ResponseWriter.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// Instrument returns middleware that logs and records metrics for the duration,
+// final status code and requested symbol of every request
+func Instrument(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next.ServeHTTP(rec, r)
+
+			duration := time.Since(start)
+			symbol := requestedSymbol(r)
+			metrics.RequestsTotal.WithLabelValues(strconv.Itoa(rec.statusCode), symbol).Inc()
+			metrics.RequestDuration.WithLabelValues(r.URL.Path).Observe(duration.Seconds())
+
+			logger.InfoContext(r.Context(), "request completed",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"symbol", symbol,
+				"status", rec.statusCode,
+				"duration_ms", duration.Milliseconds())
+		})
+	}
+}
+
+// Symbol label values for requests without a single valid symbol, keeping the
+// label's cardinality bounded whatever clients send
+const (
+	symbolLabelInvalid = "invalid"
+	symbolLabelMulti   = "multi"
+)
+
+// requestedSymbol returns the metric label for the symbol named in the request:
+// the normalized symbol if it is valid, a fixed value for invalid or multiple
+// symbols, or an empty string if there is none
+func requestedSymbol(r *http.Request) string {
+	query := r.URL.Query()
+	if raw := query.Get("symbol"); raw != "" {
+		symbol := config.NormalizeSymbol(raw)
+		if config.ValidateSymbol(symbol) != nil {
+			return symbolLabelInvalid
+		}
+		return symbol
+	}
+	if query.Get("symbols") != "" {
+		return symbolLabelMulti
+	}
+	return ""
+}
+
+// statusRecorder captures the status code written by the wrapped handler.
+// Only the first call to WriteHeader takes effect, matching net/http.
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+}
+
+// WriteHeader records the status code and forwards it
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	if !r.wroteHeader {
+		r.statusCode = statusCode
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write marks the header as written with an implicit 200 if needed
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(p)
+}
+
+// Flush forwards to the underlying writer if it supports flushing
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack forwards to the underlying writer, recording the connection as switched
+// to another protocol
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil && !r.wroteHeader {
+		r.statusCode = http.StatusSwitchingProtocols
+		r.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
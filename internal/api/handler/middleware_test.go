@@ -0,0 +1,229 @@
+package handler
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/saedabdu/stockticker/internal/requestid"
+)
+
+func TestGzip(t *testing.T) {
+	largeBody := strings.Repeat("a", gzipMinSize*2)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		body           string
+		expectGzip     bool
+	}{
+		{name: "large body compressed", acceptEncoding: "gzip, deflate", body: largeBody, expectGzip: true},
+		{name: "small body uncompressed", acceptEncoding: "gzip", body: "ok", expectGzip: false},
+		{name: "client without gzip", acceptEncoding: "", body: largeBody, expectGzip: false},
+		{name: "gzip refused", acceptEncoding: "gzip;q=0, deflate", body: largeBody, expectGzip: false},
+		{name: "gzip refused with spaces", acceptEncoding: "deflate, gzip ; q=0.0", body: largeBody, expectGzip: false},
+		{name: "gzip weighted", acceptEncoding: "deflate, gzip;q=0.5", body: largeBody, expectGzip: true},
+		{name: "wildcard", acceptEncoding: "*", body: largeBody, expectGzip: true},
+		{name: "wildcard with gzip refused", acceptEncoding: "*, gzip;q=0", body: largeBody, expectGzip: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				io.WriteString(w, tt.body)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/stocks", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusCreated {
+				t.Errorf("expected status %d, got %d", http.StatusCreated, rec.Code)
+			}
+
+			gotGzip := rec.Header().Get("Content-Encoding") == "gzip"
+			if gotGzip != tt.expectGzip {
+				t.Fatalf("expected gzip %v, got %v", tt.expectGzip, gotGzip)
+			}
+
+			var body io.Reader = rec.Body
+			if gotGzip {
+				gz, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("error creating gzip reader: %v", err)
+				}
+				body = gz
+			}
+
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("error reading body: %v", err)
+			}
+			if string(got) != tt.body {
+				t.Errorf("expected body of length %d, got %d", len(tt.body), len(got))
+			}
+		})
+	}
+}
+
+func TestStatusRecorderKeepsFirstStatus(t *testing.T) {
+	var got int
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := Instrument(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.WriteHeader(http.StatusInternalServerError)
+		got = w.(*statusRecorder).statusCode
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/stocks?symbol=IBM", nil))
+
+	if got != http.StatusBadRequest {
+		t.Errorf("expected recorded status %d, got %d", http.StatusBadRequest, got)
+	}
+}
+
+func TestRequestedSymbol(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		want   string
+	}{
+		{name: "no symbol", target: "/stocks", want: ""},
+		{name: "valid symbol", target: "/stocks?symbol=IBM", want: "IBM"},
+		{name: "normalized symbol", target: "/stocks?symbol=%20ibm", want: "IBM"},
+		{name: "invalid symbol", target: "/stocks?symbol=NOT-A-SYMBOL", want: "invalid"},
+		{name: "multiple symbols", target: "/stocks?symbols=IBM,AAPL", want: "multi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := requestedSymbol(httptest.NewRequest(http.MethodGet, tt.target, nil)); got != tt.want {
+				t.Errorf("expected label %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		wantStatus    int
+	}{
+		{name: "disabled", token: "", authorization: "", wantStatus: http.StatusOK},
+		{name: "valid token", token: "secret", authorization: "Bearer secret", wantStatus: http.StatusOK},
+		{name: "missing header", token: "secret", authorization: "", wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", token: "secret", authorization: "Bearer guess", wantStatus: http.StatusUnauthorized},
+		{name: "wrong scheme", token: "secret", authorization: "Basic secret", wantStatus: http.StatusUnauthorized},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/stocks", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+
+			Auth(tt.token)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Errorf("expected WWW-Authenticate Bearer, got %q", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	tests := []struct {
+		name          string
+		origin        string
+		requestMethod string
+		wantStatus    int
+		wantOrigin    string
+	}{
+		{name: "allowed GET", origin: "https://app.example", requestMethod: http.MethodGet, wantStatus: http.StatusNoContent, wantOrigin: "https://app.example"},
+		{name: "allowed POST", origin: "https://app.example", requestMethod: http.MethodPost, wantStatus: http.StatusNoContent, wantOrigin: "https://app.example"},
+		{name: "allowed DELETE", origin: "https://app.example", requestMethod: http.MethodDelete, wantStatus: http.StatusNoContent, wantOrigin: "https://app.example"},
+		{name: "other origin", origin: "https://evil.example", requestMethod: http.MethodPost, wantStatus: http.StatusOK, wantOrigin: ""},
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/prefetch", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			rec := httptest.NewRecorder()
+
+			CORS([]string{"https://app.example"})(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("expected Access-Control-Allow-Origin %q, got %q", tt.wantOrigin, got)
+			}
+			if tt.wantStatus == http.StatusNoContent && !strings.Contains(rec.Header().Get("Access-Control-Allow-Methods"), tt.requestMethod) {
+				t.Errorf("expected Access-Control-Allow-Methods to include %s, got %q", tt.requestMethod, rec.Header().Get("Access-Control-Allow-Methods"))
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expectID string
+	}{
+		{name: "client ID echoed", header: "abc-123", expectID: "abc-123"},
+		{name: "missing ID generated", header: ""},
+		{name: "unprintable ID replaced", header: "bad id\n"},
+		{name: "overlong ID replaced", header: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var contextID string
+			h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				contextID = requestid.FromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/stocks", nil)
+			if tt.header != "" {
+				req.Header.Set(requestid.Header, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			got := rec.Header().Get(requestid.Header)
+			if got == "" || got != contextID {
+				t.Fatalf("expected matching response and context IDs, got %q and %q", got, contextID)
+			}
+			if tt.expectID != "" && got != tt.expectID {
+				t.Errorf("expected ID %q, got %q", tt.expectID, got)
+			}
+			if tt.expectID == "" && got == tt.header {
+				t.Errorf("expected a generated ID, got the client's %q", got)
+			}
+		})
+	}
+}
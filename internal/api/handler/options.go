@@ -0,0 +1,428 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	// Embed the time zone database for the tz parameter since the runtime image may not include one
+	_ "time/tzdata"
+
+	"github.com/saedabdu/stockticker/internal/service"
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+// Supported values of the order query parameter
+const (
+	orderAsc  = "asc"
+	orderDesc = "desc"
+)
+
+// Named values of the date_format query parameter; any other value is a Go time layout
+const (
+	dateFormatRFC3339 = "rfc3339"
+	dateFormatUnix    = "unix"
+)
+
+const (
+	// timestampLayout is the format of intraday time series keys
+	timestampLayout = "2006-01-02 15:04:05"
+	// defaultSourceTimeZone is assumed when the provider does not report a time zone
+	defaultSourceTimeZone = "US/Eastern"
+	// maxPrecision is the largest number of decimals the precision parameter accepts
+	maxPrecision = 6
+)
+
+// responseOptions holds per-request presentation settings that are applied
+// after the (possibly cached) stock data has been retrieved
+type responseOptions struct {
+	ascending bool
+	// smaWindow is the number of days in the moving average, or zero for none
+	smaWindow int
+	// emaWindow is the number of days in the exponential moving average, or zero for none
+	emaWindow int
+	// location converts timestamps to another time zone when set
+	location *time.Location
+	// dateFormat is a time layout or dateFormatUnix to rewrite dates in, or empty
+	// to leave them as the provider returned them
+	dateFormat string
+	// vwap adds the volume-weighted average price
+	vwap bool
+	// returns adds the day-over-day percentage change series
+	returns bool
+	// round limits prices and price statistics to precision decimals
+	round     bool
+	precision int
+	// paginate returns only the prices from offset, at most limit of them when limit is positive
+	paginate bool
+	offset   int
+	limit    int
+}
+
+// parseResponseOptions reads the presentation query parameters from the request
+func parseResponseOptions(r *http.Request) (responseOptions, error) {
+	var opts responseOptions
+
+	switch order := r.URL.Query().Get("order"); order {
+	case "", orderDesc:
+	case orderAsc:
+		opts.ascending = true
+	default:
+		return responseOptions{}, fmt.Errorf("invalid order %q: must be %s or %s", order, orderAsc, orderDesc)
+	}
+
+	if query := r.URL.Query(); query.Has("sma") {
+		window, err := strconv.Atoi(query.Get("sma"))
+		if err != nil {
+			return responseOptions{}, fmt.Errorf("invalid sma parameter: %w", err)
+		}
+		if window <= 0 || window > maxDays {
+			return responseOptions{}, fmt.Errorf("sma parameter must be between 1 and %d, got %d", maxDays, window)
+		}
+		opts.smaWindow = window
+	}
+
+	if query := r.URL.Query(); query.Has("ema") {
+		window, err := strconv.Atoi(query.Get("ema"))
+		if err != nil {
+			return responseOptions{}, fmt.Errorf("invalid ema parameter: %w", err)
+		}
+		if window <= 0 || window > maxDays {
+			return responseOptions{}, fmt.Errorf("ema parameter must be between 1 and %d, got %d", maxDays, window)
+		}
+		opts.emaWindow = window
+	}
+
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		location, err := time.LoadLocation(tz)
+		if err != nil {
+			return responseOptions{}, fmt.Errorf("invalid tz %q: must be an IANA time zone such as Europe/London", tz)
+		}
+		opts.location = location
+	}
+
+	if format := r.URL.Query().Get("date_format"); format != "" {
+		dateFormat, err := parseDateFormat(format)
+		if err != nil {
+			return responseOptions{}, err
+		}
+		opts.dateFormat = dateFormat
+	}
+
+	if raw := r.URL.Query().Get("vwap"); raw != "" {
+		vwap, err := strconv.ParseBool(raw)
+		if err != nil {
+			return responseOptions{}, fmt.Errorf("invalid vwap parameter: must be true or false")
+		}
+		opts.vwap = vwap
+	}
+
+	if raw := r.URL.Query().Get("returns"); raw != "" {
+		returns, err := strconv.ParseBool(raw)
+		if err != nil {
+			return responseOptions{}, fmt.Errorf("invalid returns parameter: must be true or false")
+		}
+		opts.returns = returns
+	}
+
+	if query := r.URL.Query(); query.Has("precision") {
+		precision, err := strconv.Atoi(query.Get("precision"))
+		if err != nil {
+			return responseOptions{}, fmt.Errorf("invalid precision parameter: %w", err)
+		}
+		if precision < 0 || precision > maxPrecision {
+			return responseOptions{}, fmt.Errorf("precision parameter must be between 0 and %d, got %d", maxPrecision, precision)
+		}
+		opts.round = true
+		opts.precision = precision
+	}
+
+	if query := r.URL.Query(); query.Has("offset") || query.Has("limit") {
+		offset, err := parseNonNegative(query, "offset")
+		if err != nil {
+			return responseOptions{}, err
+		}
+		limit, err := parseNonNegative(query, "limit")
+		if err != nil {
+			return responseOptions{}, err
+		}
+		opts.paginate = true
+		opts.offset = offset
+		opts.limit = limit
+	}
+
+	return opts, nil
+}
+
+// parseDateFormat resolves a date_format value to a time layout or dateFormatUnix.
+// A layout must contain at least one element of the reference time, since any
+// other string would replace every date with itself.
+func parseDateFormat(format string) (string, error) {
+	switch format {
+	case dateFormatRFC3339:
+		return time.RFC3339, nil
+	case dateFormatUnix:
+		return dateFormatUnix, nil
+	}
+
+	probe := time.Date(2001, time.February, 3, 4, 5, 6, 0, time.UTC)
+	if probe.Format(format) == format {
+		return "", fmt.Errorf("invalid date_format %q: must be %s, %s or a Go time layout such as 2006-01-02", format, dateFormatRFC3339, dateFormatUnix)
+	}
+	return format, nil
+}
+
+// parseNonNegative parses the named query parameter as a non-negative integer,
+// returning zero when it is absent
+func parseNonNegative(query url.Values, name string) (int, error) {
+	if !query.Has(name) {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(query.Get(name))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s parameter: %w", name, err)
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("%s parameter must not be negative, got %d", name, value)
+	}
+	return value, nil
+}
+
+// apply returns a copy of stockData with the options applied.
+// The original is left untouched since it may be shared through the cache.
+func (o responseOptions) apply(stockData *models.StockData) *models.StockData {
+	result := *stockData
+
+	if o.location != nil {
+		convertTimeZone(&result, o.location)
+	}
+
+	if o.smaWindow > 0 {
+		result.SMA = service.MovingAverage(result.Prices, o.smaWindow, result.PriceField)
+	}
+
+	if o.emaWindow > 0 {
+		result.EMA = service.ExponentialMovingAverage(result.Prices, o.emaWindow, result.PriceField)
+	}
+
+	if o.returns {
+		result.Returns = service.Returns(result.Prices, result.PriceField)
+	}
+
+	if o.vwap {
+		if vwap, ok := service.VWAP(result.Prices, result.PriceField); ok {
+			result.VWAP = &vwap
+		}
+	}
+
+	// Rounding comes last so derived values are computed at full precision
+	if o.round {
+		roundPrices(&result, o.precision)
+	}
+
+	if o.ascending {
+		result.Prices = reversed(result.Prices)
+		result.SMA = reversed(result.SMA)
+		result.EMA = reversed(result.EMA)
+		result.Returns = reversed(result.Returns)
+	}
+
+	// Paging comes after ordering so offsets walk the prices in the order returned,
+	// and after the statistics so they still cover the whole window
+	if o.paginate {
+		paginate(&result, o.offset, o.limit)
+	}
+
+	// Dates are rewritten last since paging and the time zone conversion match on them
+	if o.dateFormat != "" {
+		formatDates(&result, o.dateFormat)
+	}
+
+	return &result
+}
+
+// paginate narrows the prices of stockData to the page starting at offset with
+// at most limit entries, or all remaining entries when limit is zero. The moving
+// averages and returns are narrowed to the dates on the page.
+func paginate(stockData *models.StockData, offset, limit int) {
+	total := len(stockData.Prices)
+	start := min(offset, total)
+	end := total
+	if limit > 0 {
+		end = min(start+limit, total)
+	}
+
+	page := &models.Page{Offset: offset, Limit: limit, Total: total}
+	if end < total {
+		next := end
+		page.NextOffset = &next
+	}
+	stockData.Page = page
+
+	stockData.Prices = stockData.Prices[start:end:end]
+
+	dates := make(map[string]bool, len(stockData.Prices))
+	for _, price := range stockData.Prices {
+		dates[price.Date] = true
+	}
+	stockData.SMA = seriesOnDates(stockData.SMA, dates)
+	stockData.EMA = seriesOnDates(stockData.EMA, dates)
+	stockData.Returns = seriesOnDates(stockData.Returns, dates)
+}
+
+// seriesOnDates returns the points of series whose date is in dates, or nil when series is nil
+func seriesOnDates(series []models.SeriesPoint, dates map[string]bool) []models.SeriesPoint {
+	if series == nil {
+		return nil
+	}
+	result := []models.SeriesPoint{}
+	for _, point := range series {
+		if dates[point.Date] {
+			result = append(result, point)
+		}
+	}
+	return result
+}
+
+// reversed returns a reversed copy of values
+func reversed[T any](values []T) []T {
+	if values == nil {
+		return nil
+	}
+	result := make([]T, len(values))
+	for i, v := range values {
+		result[len(result)-1-i] = v
+	}
+	return result
+}
+
+// convertTimeZone rewrites the timestamps of stockData in location, replacing
+// its Prices with a converted copy. Date-only values, as used by daily and longer
+// series, are left unchanged since shifting midnight would move them to another day.
+func convertTimeZone(stockData *models.StockData, location *time.Location) {
+	source, err := time.LoadLocation(stockData.TimeZone)
+	if stockData.TimeZone == "" || err != nil {
+		source, _ = time.LoadLocation(defaultSourceTimeZone)
+	}
+
+	prices := make([]models.StockPrice, len(stockData.Prices))
+	for i, price := range stockData.Prices {
+		price.Date = convertTimestamp(price.Date, source, location)
+		prices[i] = price
+	}
+	stockData.Prices = prices
+	stockData.LastRefreshed = convertTimestamp(stockData.LastRefreshed, source, location)
+	stockData.TimeZone = location.String()
+}
+
+// convertTimestamp converts an intraday timestamp from one location to another,
+// returning any other value unchanged
+func convertTimestamp(value string, from, to *time.Location) string {
+	t, err := time.ParseInLocation(timestampLayout, value, from)
+	if err != nil {
+		return value
+	}
+	return t.In(to).Format(timestampLayout)
+}
+
+// formatDates rewrites the dates and timestamps of stockData in format, a time
+// layout or dateFormatUnix, replacing its Prices and derived series with
+// rewritten copies. Values are read in the data's time zone; daily and longer
+// dates are taken as midnight.
+func formatDates(stockData *models.StockData, format string) {
+	location, err := time.LoadLocation(stockData.TimeZone)
+	if stockData.TimeZone == "" || err != nil {
+		location, _ = time.LoadLocation(defaultSourceTimeZone)
+	}
+	formatDate := func(value string) string {
+		return formatTimestamp(value, location, format)
+	}
+
+	prices := make([]models.StockPrice, len(stockData.Prices))
+	for i, price := range stockData.Prices {
+		price.Date = formatDate(price.Date)
+		prices[i] = price
+	}
+	stockData.Prices = prices
+
+	formatSeries := func(series []models.SeriesPoint) []models.SeriesPoint {
+		if series == nil {
+			return nil
+		}
+		formatted := make([]models.SeriesPoint, len(series))
+		for i, point := range series {
+			point.Date = formatDate(point.Date)
+			formatted[i] = point
+		}
+		return formatted
+	}
+	stockData.SMA = formatSeries(stockData.SMA)
+	stockData.EMA = formatSeries(stockData.EMA)
+	stockData.Returns = formatSeries(stockData.Returns)
+
+	stockData.LastRefreshed = formatDate(stockData.LastRefreshed)
+}
+
+// formatTimestamp rewrites an intraday timestamp or a date in location in format,
+// returning any other value unchanged
+func formatTimestamp(value string, location *time.Location, format string) string {
+	t, err := time.ParseInLocation(timestampLayout, value, location)
+	if err != nil {
+		// Daily and longer series are keyed by date, in the layout of the from and to parameters
+		if t, err = time.ParseInLocation(dateLayout, value, location); err != nil {
+			return value
+		}
+	}
+
+	if format == dateFormatUnix {
+		return strconv.FormatInt(t.Unix(), 10)
+	}
+	return t.Format(format)
+}
+
+// roundPrices rounds the prices, price statistics and derived series of stockData
+// to precision decimals, replacing its slices with rounded copies
+func roundPrices(stockData *models.StockData, precision int) {
+	scale := math.Pow10(precision)
+	round := func(v float64) float64 {
+		return math.Round(v*scale) / scale
+	}
+
+	prices := make([]models.StockPrice, len(stockData.Prices))
+	for i, price := range stockData.Prices {
+		price.Open = round(price.Open)
+		price.High = round(price.High)
+		price.Low = round(price.Low)
+		price.Close = round(price.Close)
+		prices[i] = price
+	}
+	stockData.Prices = prices
+
+	roundSeries := func(series []models.SeriesPoint) []models.SeriesPoint {
+		if series == nil {
+			return nil
+		}
+		rounded := make([]models.SeriesPoint, len(series))
+		for i, point := range series {
+			point.Value = round(point.Value)
+			rounded[i] = point
+		}
+		return rounded
+	}
+	stockData.SMA = roundSeries(stockData.SMA)
+	stockData.EMA = roundSeries(stockData.EMA)
+	stockData.Returns = roundSeries(stockData.Returns)
+
+	stockData.Average = round(stockData.Average)
+	stockData.Median = round(stockData.Median)
+	stockData.Min = round(stockData.Min)
+	stockData.Max = round(stockData.Max)
+	stockData.StdDev = round(stockData.StdDev)
+	stockData.PercentChange = round(stockData.PercentChange)
+	if stockData.VWAP != nil {
+		vwap := round(*stockData.VWAP)
+		stockData.VWAP = &vwap
+	}
+}
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+func TestApplyTimeZone(t *testing.T) {
+	tests := []struct {
+		name     string
+		tz       string
+		data     models.StockData
+		wantDate string
+		wantZone string
+	}{
+		{
+			name:     "intraday timestamps are converted",
+			tz:       "Europe/London",
+			data:     models.StockData{TimeZone: "US/Eastern", Prices: []models.StockPrice{{Date: "2023-01-06 16:00:00"}}},
+			wantDate: "2023-01-06 21:00:00",
+			wantZone: "Europe/London",
+		},
+		{
+			name:     "daily dates are unchanged",
+			tz:       "Asia/Tokyo",
+			data:     models.StockData{TimeZone: "US/Eastern", Prices: []models.StockPrice{{Date: "2023-01-06"}}},
+			wantDate: "2023-01-06",
+			wantZone: "Asia/Tokyo",
+		},
+		{
+			name:     "missing source zone defaults to US/Eastern",
+			tz:       "UTC",
+			data:     models.StockData{Prices: []models.StockPrice{{Date: "2023-07-06 16:00:00"}}},
+			wantDate: "2023-07-06 20:00:00",
+			wantZone: "UTC",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseResponseOptions(httptest.NewRequest(http.MethodGet, "/stocks?tz="+tt.tz, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			original := tt.data.Prices[0].Date
+
+			result := opts.apply(&tt.data)
+
+			if result.Prices[0].Date != tt.wantDate {
+				t.Errorf("expected date %s, got %s", tt.wantDate, result.Prices[0].Date)
+			}
+			if result.TimeZone != tt.wantZone {
+				t.Errorf("expected time zone %s, got %s", tt.wantZone, result.TimeZone)
+			}
+			if tt.data.Prices[0].Date != original {
+				t.Errorf("expected the original data to be left untouched, got %s", tt.data.Prices[0].Date)
+			}
+		})
+	}
+}
+
+func TestParseResponseOptionsRejectsUnknownTimeZone(t *testing.T) {
+	if _, err := parseResponseOptions(httptest.NewRequest(http.MethodGet, "/stocks?tz=Mars/Olympus", nil)); err == nil {
+		t.Error("expected error for unknown time zone, got nil")
+	}
+}
+
+func TestApplyPrecision(t *testing.T) {
+	data := models.StockData{
+		Prices:  []models.StockPrice{{Date: "2023-01-06", Close: 143.7049}, {Date: "2023-01-05", Close: 146.8281}},
+		Average: 145.26666666666668,
+	}
+
+	opts, err := parseResponseOptions(httptest.NewRequest(http.MethodGet, "/stocks?precision=2", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := opts.apply(&data)
+
+	if result.Prices[0].Close != 143.7 || result.Prices[1].Close != 146.83 {
+		t.Errorf("expected closes 143.7 and 146.83, got %v and %v", result.Prices[0].Close, result.Prices[1].Close)
+	}
+	if result.Average != 145.27 {
+		t.Errorf("expected average 145.27, got %v", result.Average)
+	}
+	if data.Prices[0].Close != 143.7049 {
+		t.Errorf("expected the original data to be left untouched, got %v", data.Prices[0].Close)
+	}
+
+	for _, invalid := range []string{"-1", "7", "two"} {
+		if _, err := parseResponseOptions(httptest.NewRequest(http.MethodGet, "/stocks?precision="+invalid, nil)); err == nil {
+			t.Errorf("expected an error for precision %q", invalid)
+		}
+	}
+}
+
+func TestApplyPagination(t *testing.T) {
+	data := models.StockData{
+		Prices: []models.StockPrice{
+			{Date: "2023-01-06", Close: 5},
+			{Date: "2023-01-05", Close: 4},
+			{Date: "2023-01-04", Close: 3},
+			{Date: "2023-01-03", Close: 2},
+			{Date: "2023-01-02", Close: 1},
+		},
+		Average: 3,
+		SMA:     []models.SeriesPoint{{Date: "2023-01-06", Value: 4.5}, {Date: "2023-01-05", Value: 3.5}},
+	}
+
+	tests := []struct {
+		name      string
+		target    string
+		wantDates []string
+		wantNext  *int
+		wantSMA   int
+	}{
+		{name: "first page", target: "/stocks?limit=2", wantDates: []string{"2023-01-06", "2023-01-05"}, wantNext: intPtr(2), wantSMA: 2},
+		{name: "middle page", target: "/stocks?offset=2&limit=2", wantDates: []string{"2023-01-04", "2023-01-03"}, wantNext: intPtr(4)},
+		{name: "last page", target: "/stocks?offset=4&limit=2", wantDates: []string{"2023-01-02"}},
+		{name: "offset only", target: "/stocks?offset=3", wantDates: []string{"2023-01-03", "2023-01-02"}},
+		{name: "past the end", target: "/stocks?offset=10", wantDates: []string{}},
+		{name: "ascending", target: "/stocks?order=asc&limit=2", wantDates: []string{"2023-01-02", "2023-01-03"}, wantNext: intPtr(2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseResponseOptions(httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result := opts.apply(&data)
+
+			var dates []string
+			for _, price := range result.Prices {
+				dates = append(dates, price.Date)
+			}
+			if strings.Join(dates, ",") != strings.Join(tt.wantDates, ",") {
+				t.Errorf("expected dates %v, got %v", tt.wantDates, dates)
+			}
+			if result.Page == nil || result.Page.Total != 5 {
+				t.Fatalf("expected a page with a total of 5, got %+v", result.Page)
+			}
+			if (result.Page.NextOffset == nil) != (tt.wantNext == nil) || (tt.wantNext != nil && *result.Page.NextOffset != *tt.wantNext) {
+				t.Errorf("expected next offset %v, got %v", tt.wantNext, result.Page.NextOffset)
+			}
+			if len(result.SMA) != tt.wantSMA {
+				t.Errorf("expected %d moving average points on the page, got %d", tt.wantSMA, len(result.SMA))
+			}
+			if result.Average != 3 {
+				t.Errorf("expected the average of the full window, got %v", result.Average)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"offset=-1", "limit=-5", "limit=ten"} {
+		if _, err := parseResponseOptions(httptest.NewRequest(http.MethodGet, "/stocks?"+invalid, nil)); err == nil {
+			t.Errorf("expected an error for %s", invalid)
+		}
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestApplyReturns(t *testing.T) {
+	data := &models.StockData{Prices: []models.StockPrice{
+		{Date: "2023-01-06", Close: 12},
+		{Date: "2023-01-05", Close: 10},
+		{Date: "2023-01-04", Close: 8},
+	}}
+
+	opts, err := parseResponseOptions(httptest.NewRequest(http.MethodGet, "/stocks?returns=true&order=asc&limit=1", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := opts.apply(data)
+
+	// The oldest date has no return, and paging keeps only the first ascending date
+	if len(result.Returns) != 0 {
+		t.Errorf("expected no returns on the first ascending page, got %v", result.Returns)
+	}
+
+	opts, _ = parseResponseOptions(httptest.NewRequest(http.MethodGet, "/stocks?returns=true&order=asc", nil))
+	result = opts.apply(data)
+	if len(result.Returns) != 2 || result.Returns[0].Date != "2023-01-05" || result.Returns[1].Value != 20 {
+		t.Errorf("expected ascending returns of 25%% then 20%%, got %v", result.Returns)
+	}
+
+	if _, err := parseResponseOptions(httptest.NewRequest(http.MethodGet, "/stocks?returns=maybe", nil)); err == nil {
+		t.Error("expected an error for an invalid returns value")
+	}
+}
+
+func TestApplyDateFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		data     models.StockData
+		wantDate string
+	}{
+		{
+			name:     "daily date as rfc3339",
+			format:   "rfc3339",
+			data:     models.StockData{TimeZone: "US/Eastern", Prices: []models.StockPrice{{Date: "2023-01-06"}}},
+			wantDate: "2023-01-06T00:00:00-05:00",
+		},
+		{
+			name:     "intraday timestamp as unix",
+			format:   "unix",
+			data:     models.StockData{TimeZone: "US/Eastern", Prices: []models.StockPrice{{Date: "2023-01-06 16:00:00"}}},
+			wantDate: "1673038800",
+		},
+		{
+			name:     "go layout",
+			format:   "Jan 2, 2006",
+			data:     models.StockData{Prices: []models.StockPrice{{Date: "2023-01-06"}}},
+			wantDate: "Jan 6, 2023",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := "/stocks?date_format=" + url.QueryEscape(tt.format)
+			opts, err := parseResponseOptions(httptest.NewRequest(http.MethodGet, target, nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			original := tt.data.Prices[0].Date
+
+			result := opts.apply(&tt.data)
+
+			if result.Prices[0].Date != tt.wantDate {
+				t.Errorf("expected date %s, got %s", tt.wantDate, result.Prices[0].Date)
+			}
+			if tt.data.Prices[0].Date != original {
+				t.Errorf("expected the original data to be left untouched, got %s", tt.data.Prices[0].Date)
+			}
+		})
+	}
+}
+
+func TestParseResponseOptionsRejectsInvalidDateFormat(t *testing.T) {
+	_, err := parseResponseOptions(httptest.NewRequest(http.MethodGet, "/stocks?date_format=epoch", nil))
+	if err == nil || !strings.Contains(err.Error(), "date_format") {
+		t.Errorf("expected an invalid date_format error, got %v", err)
+	}
+}
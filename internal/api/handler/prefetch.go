@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/saedabdu/stockticker/internal/api"
+)
+
+// maxPrefetchBodyBytes bounds the size of a /prefetch request body
+const maxPrefetchBodyBytes = 64 << 10
+
+// HandlePrefetch handles requests to the /prefetch endpoint, fetching the default
+// window of each listed symbol into the cache and reporting the outcome per symbol
+func (h *StockHandler) HandlePrefetch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.sendMethodNotAllowed(w, http.MethodPost)
+		return
+	}
+
+	var request api.PrefetchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPrefetchBodyBytes)).Decode(&request); err != nil {
+		h.sendErrorResponse(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	symbols, err := validateSymbols(request.Symbols)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results := h.stockService.PrefetchSymbols(r.Context(), symbols)
+
+	responses := make([]api.PrefetchResult, 0, len(results))
+	for _, result := range results {
+		response := api.PrefetchResult{Symbol: result.Symbol, Cached: result.Err == nil}
+		if result.Err != nil {
+			response.Error = result.Err.Error()
+		}
+		responses = append(responses, response)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.sendJSONResponse(w, responses, http.StatusOK)
+}
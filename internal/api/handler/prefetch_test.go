@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/api"
+	"github.com/saedabdu/stockticker/internal/cache"
+	"github.com/saedabdu/stockticker/internal/client"
+	"github.com/saedabdu/stockticker/internal/config"
+	"github.com/saedabdu/stockticker/internal/service"
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+// stubProvider answers every symbol with one price, except FAIL which is unknown
+type stubProvider struct{}
+
+func (stubProvider) GetStockData(ctx context.Context, symbol string, days int, interval client.Interval) (*models.AlphaVantageResponse, error) {
+	if symbol == "FAIL" {
+		return nil, client.ErrInvalidSymbol
+	}
+	return &models.AlphaVantageResponse{
+		TimeSeries: map[string]models.DailyPrice{
+			"2023-01-03": {Open: "1", High: "1", Low: "1", Close: "1", Volume: "1"},
+		},
+	}, nil
+}
+
+// newTestHandler returns a handler backed by stubProvider and an in-memory cache
+func newTestHandler(cfg *config.Config) *StockHandler {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewStockHandler(cfg, service.New(cfg, stubProvider{}, cache.New(0), logger), logger)
+}
+
+func TestHandlePrefetch(t *testing.T) {
+	h := newTestHandler(&config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}})
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		want       []api.PrefetchResult
+	}{
+		{
+			name:       "per-symbol results",
+			method:     http.MethodPost,
+			body:       `{"symbols": ["AAPL", "FAIL", "AAPL"]}`,
+			wantStatus: http.StatusOK,
+			want: []api.PrefetchResult{
+				{Symbol: "AAPL", Cached: true},
+				{Symbol: "FAIL", Error: client.ErrInvalidSymbol.Error()},
+			},
+		},
+		{
+			name:       "symbols are normalized before deduplication",
+			method:     http.MethodPost,
+			body:       `{"symbols": [" aapl", "AAPL"]}`,
+			wantStatus: http.StatusOK,
+			want:       []api.PrefetchResult{{Symbol: "AAPL", Cached: true}},
+		},
+		{name: "invalid symbol", method: http.MethodPost, body: `{"symbols": ["TOOLONG"]}`, wantStatus: http.StatusBadRequest},
+		{name: "no symbols", method: http.MethodPost, body: `{"symbols": []}`, wantStatus: http.StatusBadRequest},
+		{name: "malformed body", method: http.MethodPost, body: `{"symbols":`, wantStatus: http.StatusBadRequest},
+		{name: "wrong method", method: http.MethodGet, wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.HandlePrefetch(rec, httptest.NewRequest(tt.method, "/prefetch", strings.NewReader(tt.body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.want == nil {
+				return
+			}
+
+			var got []api.PrefetchResult
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("error decoding response: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d results, got %d", len(tt.want), len(got))
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("result %d: expected %+v, got %+v", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
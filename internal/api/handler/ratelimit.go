@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// clientIdleTimeout is how long a client's bucket is kept after its last request
+const clientIdleTimeout = 10 * time.Minute
+
+// RateLimiter limits requests per client IP using a token bucket per client
+type RateLimiter struct {
+	limit rate.Limit
+	burst int
+	// trustedProxies are the peers whose X-Forwarded-For header is honored
+	trustedProxies []netip.Prefix
+
+	mu      sync.Mutex
+	clients map[string]*clientBucket
+
+	// janitor state, guarded by janitorMu
+	janitorMu sync.Mutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// clientBucket is the token bucket of a single client
+type clientBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates a limiter allowing each client requestsPerMinute
+// requests per minute, in bursts of up to requestsPerMinute requests. Clients
+// are identified by their address, or by X-Forwarded-For for requests relayed
+// by one of trustedProxies.
+func NewRateLimiter(requestsPerMinute int, trustedProxies []netip.Prefix) *RateLimiter {
+	return &RateLimiter{
+		limit:          rate.Limit(float64(requestsPerMinute) / 60),
+		burst:          requestsPerMinute,
+		trustedProxies: trustedProxies,
+		clients:        make(map[string]*clientBucket),
+	}
+}
+
+// Limit is middleware that answers requests over the client's limit with 429
+// and a Retry-After header giving the seconds until a request would be allowed
+func (l *RateLimiter) Limit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reservation := l.bucket(l.clientIP(r)).Reserve()
+		if delay := reservation.Delay(); delay > 0 {
+			reservation.Cancel()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			writeErrorResponse(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bucket returns the token bucket for ip, creating it on first use
+func (l *RateLimiter) bucket(ip string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	client, ok := l.clients[ip]
+	if !ok {
+		client = &clientBucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[ip] = client
+	}
+	client.lastSeen = time.Now()
+
+	return client.limiter
+}
+
+// Cleanup removes the buckets of clients idle for longer than clientIdleTimeout
+func (l *RateLimiter) Cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cutoff := time.Now().Add(-clientIdleTimeout)
+	for ip, client := range l.clients {
+		if client.lastSeen.Before(cutoff) {
+			delete(l.clients, ip)
+		}
+	}
+}
+
+// StartJanitor starts a background goroutine that calls Cleanup every interval.
+// Calling StartJanitor while a janitor is already running has no effect.
+func (l *RateLimiter) StartJanitor(interval time.Duration) {
+	l.janitorMu.Lock()
+	defer l.janitorMu.Unlock()
+
+	if l.stop != nil {
+		return
+	}
+
+	l.stop = make(chan struct{})
+	l.wg.Add(1)
+	go l.runJanitor(interval, l.stop)
+}
+
+// Stop halts the janitor goroutine and waits for it to exit.
+// It is safe to call Stop multiple times or when no janitor is running.
+func (l *RateLimiter) Stop() {
+	l.janitorMu.Lock()
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+	l.janitorMu.Unlock()
+
+	l.wg.Wait()
+}
+
+// runJanitor periodically removes idle clients until stop is closed
+func (l *RateLimiter) runJanitor(interval time.Duration, stop <-chan struct{}) {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.Cleanup()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// clientIP returns the originating client address. It is the host of RemoteAddr
+// unless that peer is a trusted proxy, in which case it is the rightmost
+// X-Forwarded-For entry that is not itself a trusted proxy. Entries left of it
+// are written by the client and can be forged, so they are never used.
+func (l *RateLimiter) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !l.trusted(host) {
+		return host
+	}
+
+	var entries []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, entry := range strings.Split(header, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	client := host
+	for i := len(entries) - 1; i >= 0; i-- {
+		client = entries[i]
+		if !l.trusted(client) {
+			break
+		}
+	}
+	return client
+}
+
+// trusted reports whether ip is one of the trusted proxies
+func (l *RateLimiter) trusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range l.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(2, nil)
+	handler := limiter.Limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	request := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/stocks", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := request("10.0.0.1:1234", ""); rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200 within the burst, got %d", i+1, rec.Code)
+		}
+	}
+
+	rec := request("10.0.0.1:5678", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429 over the limit, got %d", rec.Code)
+	}
+	if retryAfter := rec.Header().Get("Retry-After"); retryAfter != "30" {
+		t.Errorf("expected Retry-After 30, got %q", retryAfter)
+	}
+
+	if rec := request("10.0.0.2:1234", ""); rec.Code != http.StatusOK {
+		t.Errorf("expected another client to be allowed, got %d", rec.Code)
+	}
+	if rec := request("10.0.0.1:1234", "10.0.0.3"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("expected a spoofed X-Forwarded-For from an untrusted peer to be ignored, got %d", rec.Code)
+	}
+
+	limiter.clients["10.0.0.2"].lastSeen = time.Now().Add(-2 * clientIdleTimeout)
+	limiter.Cleanup()
+	if _, ok := limiter.clients["10.0.0.2"]; ok {
+		t.Errorf("expected idle client to be removed")
+	}
+	if len(limiter.clients) != 1 {
+		t.Errorf("expected 1 active client to remain, got %d", len(limiter.clients))
+	}
+}
+
+func TestRateLimiterClientIP(t *testing.T) {
+	limiter := NewRateLimiter(1, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")})
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		want         string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:1234", want: "203.0.113.7"},
+		{name: "untrusted peer", remoteAddr: "203.0.113.7:1234", forwardedFor: []string{"198.51.100.1"}, want: "203.0.113.7"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.1:1234", forwardedFor: []string{"198.51.100.1"}, want: "198.51.100.1"},
+		{name: "spoofed entry left of client", remoteAddr: "10.0.0.1:1234", forwardedFor: []string{"1.2.3.4, 198.51.100.1"}, want: "198.51.100.1"},
+		{name: "proxy chain", remoteAddr: "10.0.0.1:1234", forwardedFor: []string{"1.2.3.4, 198.51.100.1", "10.0.0.2"}, want: "198.51.100.1"},
+		{name: "trusted proxy without header", remoteAddr: "10.0.0.1:1234", want: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/stocks", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if got := limiter.clientIP(req); got != tt.want {
+				t.Errorf("expected client %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -2,78 +2,1010 @@ package handler
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/saedabdu/stockticker/internal/api"
+	"github.com/saedabdu/stockticker/internal/client"
+	"github.com/saedabdu/stockticker/internal/config"
 	"github.com/saedabdu/stockticker/internal/service"
+	"github.com/saedabdu/stockticker/internal/version"
+	"github.com/saedabdu/stockticker/pkg/models"
 )
 
+const (
+	// maxDays is the largest number of days a client may request
+	maxDays = 500
+	// maxSymbols is the largest number of symbols a client may request at once
+	maxSymbols = 10
+	// maxIntervals is the largest number of intervals a client may request at once
+	maxIntervals = 4
+	// dateLayout is the format of the from and to query parameters
+	dateLayout = "2006-01-02"
+)
+
+// currencyPattern matches a three-letter ISO 4217 currency code
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
 // StockHandler handles HTTP requests for stock data
 type StockHandler struct {
 	stockService *service.StockService
+	config       *config.Config
+	logger       *slog.Logger
 }
 
 // NewStockHandler creates a new StockHandler
-func NewStockHandler(stockService *service.StockService) *StockHandler {
+func NewStockHandler(cfg *config.Config, stockService *service.StockService, logger *slog.Logger) *StockHandler {
 	return &StockHandler{
 		stockService: stockService,
+		config:       cfg,
+		logger:       logger,
 	}
 }
 
 // HandleStocks handles requests to the /stocks endpoint
 func (h *StockHandler) HandleStocks(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodDelete {
+		h.handleInvalidate(w, r)
+		return
+	}
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		h.sendMethodNotAllowed(w, http.MethodGet, http.MethodDelete)
+		return
+	}
+
+	query, err := h.buildQuery(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts, err := parseResponseOptions(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	currency, err := resolveCurrency(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Query().Has("symbols") {
+		if r.URL.Query().Has("intervals") {
+			h.sendErrorResponse(w, "symbols and intervals parameters cannot be combined", http.StatusBadRequest)
+			return
+		}
+		h.handleMultipleStocks(w, r, query, opts, currency)
+		return
+	}
+
+	symbol, err := h.resolveSymbol(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	query.Symbol = symbol
+	if !r.URL.Query().Has("days") {
+		query.Days = h.config.NDaysFor(symbol)
+	}
 
-	stockData, err := h.stockService.GetStockData()
+	if r.URL.Query().Has("intervals") {
+		h.handleMultipleIntervals(w, r, query, opts, currency)
+		return
+	}
+
+	stockData, err := h.stockService.GetStockData(r.Context(), query)
+	if err == nil {
+		stockData, err = h.stockService.ConvertCurrency(r.Context(), stockData, currency)
+	}
 	if err != nil {
-		log.Printf("Error getting stock data: %v", err)
-		h.sendErrorResponse(w, err.Error(), http.StatusInternalServerError)
+		status := statusForError(err)
+		h.logger.ErrorContext(r.Context(), "error getting stock data",
+			"symbol", query.Symbol, "days", query.Days, "status", status, "error", err)
+		h.sendServiceError(w, err, status)
 		return
 	}
+	stockData = opts.apply(stockData)
+
+	h.setCacheControl(w, stockData.CachedAt)
+	setStaleHeader(w, stockData)
 
-	// Convert domain model to API response
-	response := api.StockResponse{
+	if wantsCSV(r) {
+		h.sendCSVResponse(w, stockData)
+		return
+	}
+	if wantsXML(r) {
+		h.sendXMLResponse(w, toStockResponse(stockData))
+		return
+	}
+
+	if fields := parseFields(r); fields != nil {
+		selected, err := selectFields(toStockResponse(stockData), fields)
+		if err != nil {
+			h.logger.ErrorContext(r.Context(), "error selecting response fields", "symbol", query.Symbol, "error", err)
+			h.sendErrorResponse(w, "error encoding response", http.StatusInternalServerError)
+			return
+		}
+		h.sendConditionalJSONResponse(w, r, selected)
+		return
+	}
+
+	h.sendConditionalJSONResponse(w, r, toStockResponse(stockData))
+}
+
+// HandleAlert handles requests to the /stocks/alert endpoint, reporting whether
+// the change between the two newest closes reached the threshold percentage in
+// either direction
+func (h *StockHandler) HandleAlert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	threshold, err := resolveThreshold(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	symbol, err := h.resolveSymbol(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Request the symbol's default window so the result is shared with /stocks through the cache
+	query := service.Query{Symbol: symbol, Days: max(h.config.NDaysFor(symbol), 2), Interval: client.IntervalDaily}
+	stockData, err := h.stockService.GetStockData(r.Context(), query)
+	if err != nil {
+		status := statusForError(err)
+		h.logger.ErrorContext(r.Context(), "error getting stock data",
+			"symbol", query.Symbol, "days", query.Days, "status", status, "error", err)
+		h.sendServiceError(w, err, status)
+		return
+	}
+
+	change, ok := service.DailyChange(stockData.Prices)
+	if !ok {
+		h.sendErrorResponse(w, fmt.Sprintf("not enough price history for %s to compute a daily change", symbol), http.StatusNotFound)
+		return
+	}
+
+	h.setCacheControl(w, stockData.CachedAt)
+	setStaleHeader(w, stockData)
+	h.sendConditionalJSONResponse(w, r, api.AlertResponse{
+		Symbol:        stockData.Symbol,
+		Date:          stockData.Prices[0].Date,
+		ChangePercent: change,
+		Threshold:     threshold,
+		Triggered:     math.Abs(change) >= threshold,
+	})
+}
+
+// HandleAverage handles requests to the /stocks/average endpoint, returning only
+// the average close over the requested window
+func (h *StockHandler) HandleAverage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	query, err := h.buildQuery(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	symbol, err := h.resolveSymbol(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query.Symbol = symbol
+	if !r.URL.Query().Has("days") {
+		query.Days = h.config.NDaysFor(symbol)
+	}
+
+	stockData, err := h.stockService.GetStockData(r.Context(), query)
+	if err != nil {
+		status := statusForError(err)
+		h.logger.ErrorContext(r.Context(), "error getting stock data",
+			"symbol", query.Symbol, "days", query.Days, "status", status, "error", err)
+		h.sendServiceError(w, err, status)
+		return
+	}
+
+	h.setCacheControl(w, stockData.CachedAt)
+	setStaleHeader(w, stockData)
+	h.sendConditionalJSONResponse(w, r, api.AverageResponse{Symbol: stockData.Symbol, Average: stockData.Average, PriceField: stockData.PriceField})
+}
+
+// HandleExtremes handles requests to the /stocks/extremes endpoint, returning
+// the highest and lowest close over the requested window
+func (h *StockHandler) HandleExtremes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	query, err := h.buildQuery(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Only the closes are used, so share the cached data of the default price field
+	query.PriceField = ""
+
+	symbol, err := h.resolveSymbol(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query.Symbol = symbol
+	if !r.URL.Query().Has("days") {
+		query.Days = h.config.NDaysFor(symbol)
+	}
+
+	stockData, err := h.stockService.GetStockData(r.Context(), query)
+	if err != nil {
+		status := statusForError(err)
+		h.logger.ErrorContext(r.Context(), "error getting stock data",
+			"symbol", query.Symbol, "days", query.Days, "status", status, "error", err)
+		h.sendServiceError(w, err, status)
+		return
+	}
+
+	high, low, ok := service.Extremes(stockData.Prices)
+	if !ok {
+		h.sendErrorResponse(w, fmt.Sprintf("no price history for %s in the requested window", symbol), http.StatusNotFound)
+		return
+	}
+
+	h.setCacheControl(w, stockData.CachedAt)
+	setStaleHeader(w, stockData)
+	h.sendConditionalJSONResponse(w, r, api.ExtremesResponse{
+		Symbol: stockData.Symbol,
+		High:   api.PricePoint{Date: high.Date, Close: high.Close},
+		Low:    api.PricePoint{Date: low.Date, Close: low.Close},
+	})
+}
+
+// HandleCorrelation handles requests to the /stocks/correlation endpoint,
+// returning the correlation of the closes of symbols a and b over the window
+func (h *StockHandler) HandleCorrelation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	query, err := h.buildQuery(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Only the closes are used, so share the cached data of the default price field
+	query.PriceField = ""
+
+	symbols := make([]string, 0, 2)
+	for _, name := range []string{"a", "b"} {
+		symbol := config.NormalizeSymbol(r.URL.Query().Get(name))
+		if symbol == "" {
+			h.sendErrorResponse(w, fmt.Sprintf("%s parameter is required", name), http.StatusBadRequest)
+			return
+		}
+		if err := config.ValidateSymbol(symbol); err != nil {
+			h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		symbols = append(symbols, symbol)
+	}
+
+	results := h.stockService.GetMultipleStockData(r.Context(), symbols, query)
+	for _, result := range results {
+		if result.Err != nil {
+			status := statusForError(result.Err)
+			h.logger.ErrorContext(r.Context(), "error getting stock data",
+				"symbol", result.Symbol, "days", query.Days, "status", status, "error", result.Err)
+			h.sendServiceError(w, result.Err, status)
+			return
+		}
+	}
+	a, b := results[0].Data, results[1].Data
+
+	correlation, points, ok := service.Correlation(a.Prices, b.Prices)
+	if !ok {
+		h.sendErrorResponse(w, fmt.Sprintf("correlation of %s and %s is undefined: %d dates with prices for both and at least 2 distinct closes each are required",
+			symbols[0], symbols[1], points), http.StatusNotFound)
+		return
+	}
+
+	h.setCacheControl(w, oldestCachedAt(a.CachedAt, b.CachedAt))
+	setStaleHeader(w, a)
+	setStaleHeader(w, b)
+	h.sendConditionalJSONResponse(w, r, api.CorrelationResponse{
+		A:           symbols[0],
+		B:           symbols[1],
+		Points:      points,
+		Correlation: correlation,
+	})
+}
+
+// handleMultipleIntervals handles /stocks requests with an intervals parameter,
+// returning the symbol's data at each interval keyed by interval
+func (h *StockHandler) handleMultipleIntervals(w http.ResponseWriter, r *http.Request, query service.Query, opts responseOptions, currency string) {
+	if r.URL.Query().Has("interval") {
+		h.sendErrorResponse(w, "interval and intervals parameters cannot be combined", http.StatusBadRequest)
+		return
+	}
+
+	intervals, err := parseIntervals(r.URL.Query().Get("intervals"))
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if query.Adjusted {
+		for _, interval := range intervals {
+			if interval != client.IntervalDaily {
+				h.sendErrorResponse(w, fmt.Sprintf("adjusted parameter requires the daily interval, got %s", interval), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	results := h.stockService.GetMultipleIntervalData(r.Context(), query, intervals)
+
+	response := api.IntervalsResponse{Symbol: query.Symbol, Intervals: make(map[string]api.IntervalResponse, len(results))}
+	var cachedAt []time.Time
+	for _, result := range results {
+		if result.Err == nil {
+			result.Data, result.Err = h.stockService.ConvertCurrency(r.Context(), result.Data, currency)
+		}
+		if result.Err != nil {
+			h.logger.ErrorContext(r.Context(), "error getting stock data",
+				"symbol", query.Symbol, "days", query.Days, "interval", result.Interval, "error", result.Err)
+			response.Intervals[string(result.Interval)] = api.IntervalResponse{Error: result.Err.Error()}
+			continue
+		}
+		cachedAt = append(cachedAt, result.Data.CachedAt)
+		stockResponse := toStockResponse(opts.apply(result.Data))
+		response.Intervals[string(result.Interval)] = api.IntervalResponse{StockResponse: &stockResponse}
+	}
+
+	// Partial results are left uncacheable so failed intervals are retried
+	if len(cachedAt) == len(results) {
+		h.setCacheControl(w, oldestCachedAt(cachedAt...))
+	}
+	h.sendConditionalJSONResponse(w, r, response)
+}
+
+// handleInvalidate handles DELETE requests to the /stocks endpoint, removing the
+// cached data for the symbol so the next request fetches it again
+func (h *StockHandler) handleInvalidate(w http.ResponseWriter, r *http.Request) {
+	symbol, err := h.resolveSymbol(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.stockService.InvalidateSymbol(r.Context(), symbol)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleLatest handles requests to the /stocks/latest endpoint, returning only the most recent close
+func (h *StockHandler) HandleLatest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	symbol, err := h.resolveSymbol(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Request the symbol's default window so the result is shared with /stocks through the cache
+	query := service.Query{Symbol: symbol, Days: h.config.NDaysFor(symbol), Interval: client.IntervalDaily}
+	stockData, err := h.stockService.GetStockData(r.Context(), query)
+	if err != nil {
+		status := statusForError(err)
+		h.logger.ErrorContext(r.Context(), "error getting stock data",
+			"symbol", query.Symbol, "days", query.Days, "status", status, "error", err)
+		h.sendServiceError(w, err, status)
+		return
+	}
+
+	// Prices are sorted newest first
+	latest := stockData.Prices[0]
+	h.setCacheControl(w, stockData.CachedAt)
+	setStaleHeader(w, stockData)
+	h.sendConditionalJSONResponse(w, r, api.LatestResponse{Symbol: stockData.Symbol, Date: latest.Date, Close: latest.Close})
+}
+
+// handleMultipleStocks serves a /stocks request for a comma-separated list of symbols
+func (h *StockHandler) handleMultipleStocks(w http.ResponseWriter, r *http.Request, query service.Query, opts responseOptions, currency string) {
+	symbols, err := parseSymbols(r.URL.Query().Get("symbols"))
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results := h.stockService.GetMultipleStockData(r.Context(), symbols, query)
+
+	responses := make([]api.SymbolResponse, 0, len(results))
+	var cachedAt []time.Time
+	for _, result := range results {
+		if result.Err == nil {
+			result.Data, result.Err = h.stockService.ConvertCurrency(r.Context(), result.Data, currency)
+		}
+		if result.Err != nil {
+			h.logger.ErrorContext(r.Context(), "error getting stock data",
+				"symbol", result.Symbol, "days", query.Days, "error", result.Err)
+			responses = append(responses, api.SymbolResponse{Symbol: result.Symbol, Error: result.Err.Error()})
+			continue
+		}
+		cachedAt = append(cachedAt, result.Data.CachedAt)
+		response := toStockResponse(opts.apply(result.Data))
+		responses = append(responses, api.SymbolResponse{StockResponse: &response, Symbol: result.Symbol})
+	}
+
+	// Partial results are left uncacheable so failed symbols are retried
+	if len(cachedAt) == len(results) {
+		h.setCacheControl(w, oldestCachedAt(cachedAt...))
+	}
+	h.sendConditionalJSONResponse(w, r, responses)
+}
+
+// toStockResponse converts the domain model to an API response
+func toStockResponse(stockData *models.StockData) api.StockResponse {
+	return api.StockResponse{
 		Symbol:  stockData.Symbol,
 		Prices:  stockData.Prices,
 		Average: stockData.Average,
+		Median:  stockData.Median,
+		Min:     stockData.Min,
+		Max:     stockData.Max,
+		StdDev:  stockData.StdDev,
+
+		PercentChange: stockData.PercentChange,
+		Currency:      stockData.Currency,
+		LastRefreshed: stockData.LastRefreshed,
+		TimeZone:      stockData.TimeZone,
+		RequestedDays: stockData.RequestedDays,
+		ReturnedDays:  stockData.ReturnedDays,
+		PriceField:    stockData.PriceField,
+
+		CachedAt: stockData.CachedAt,
+
+		SMA:     stockData.SMA,
+		EMA:     stockData.EMA,
+		Returns: stockData.Returns,
+
+		VWAP: stockData.VWAP,
+
+		Page: stockData.Page,
+
+		Stale: stockData.Stale,
+	}
+}
+
+// HandleSearch handles requests to the /search endpoint
+func (h *StockHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	keywords := strings.TrimSpace(r.URL.Query().Get("q"))
+	if keywords == "" {
+		h.sendErrorResponse(w, "q parameter must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	matches, err := h.stockService.SearchSymbols(r.Context(), keywords)
+	if err != nil {
+		status := statusForError(err)
+		h.logger.ErrorContext(r.Context(), "error searching symbols", "keywords", keywords, "status", status, "error", err)
+		h.sendServiceError(w, err, status)
+		return
+	}
+
+	results := make([]api.SearchResult, 0, len(matches))
+	for _, match := range matches {
+		results = append(results, api.SearchResult{
+			Symbol:   match.Symbol,
+			Name:     match.Name,
+			Region:   match.Region,
+			Currency: match.Currency,
+		})
+	}
+
+	h.sendJSONResponse(w, results, http.StatusOK)
+}
+
+// HandleQuote handles requests to the /quote endpoint, returning the latest
+// quote of each symbol in the symbols parameter
+func (h *StockHandler) HandleQuote(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	symbols, err := parseSymbols(r.URL.Query().Get("symbols"))
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.stockService.GetQuotes(r.Context(), symbols)
+	if err != nil {
+		status := statusForError(err)
+		h.logger.ErrorContext(r.Context(), "error getting quotes", "symbols", symbols, "status", status, "error", err)
+		h.sendServiceError(w, err, status)
+		return
 	}
 
-	h.sendJSONResponse(w, response)
+	responses := make([]api.QuoteResponse, 0, len(results))
+	for _, result := range results {
+		if result.Err != nil {
+			h.logger.ErrorContext(r.Context(), "error getting quote", "symbol", result.Symbol, "error", result.Err)
+			responses = append(responses, api.QuoteResponse{Symbol: result.Symbol, Error: result.Err.Error()})
+			continue
+		}
+		responses = append(responses, api.QuoteResponse{
+			Symbol: result.Symbol,
+			QuoteData: &api.QuoteData{
+				Price:            result.Quote.Price,
+				Change:           result.Quote.Change,
+				ChangePercent:    result.Quote.ChangePercent,
+				LatestTradingDay: result.Quote.LatestTradingDay,
+			},
+		})
+	}
+
+	h.sendJSONResponse(w, responses, http.StatusOK)
 }
 
-// HandleHealth handles requests to the /health endpoint
+// HandleHealth handles requests to the /health endpoint. With deep=true it also
+// reports whether the upstream provider is reachable.
 func (h *StockHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		h.sendMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	deep := false
+	if raw := r.URL.Query().Get("deep"); raw != "" {
+		var err error
+		if deep, err = strconv.ParseBool(raw); err != nil {
+			h.sendErrorResponse(w, "invalid deep parameter: must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	if !h.isReady() {
+		h.sendJSONResponse(w, api.HealthResponse{Status: "degraded"}, http.StatusServiceUnavailable)
+		return
+	}
+	if !deep {
+		h.sendJSONResponse(w, api.HealthResponse{Status: "ok"}, http.StatusOK)
 		return
 	}
 
-	h.sendJSONResponse(w, api.HealthResponse{Status: "healthy"})
+	// A deep check also requires the provider to be reachable
+	if err := h.stockService.CheckUpstream(r.Context()); err != nil {
+		h.sendJSONResponse(w, api.HealthResponse{Status: "degraded", Upstream: "unreachable", Error: err.Error()}, http.StatusServiceUnavailable)
+		return
+	}
+	h.sendJSONResponse(w, api.HealthResponse{Status: "ok", Upstream: "ok"}, http.StatusOK)
 }
 
-// sendJSONResponse sends a JSON response to the client
-func (h *StockHandler) sendJSONResponse(w http.ResponseWriter, data interface{}) {
+// HandleVersion handles requests to the /version endpoint
+func (h *StockHandler) HandleVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	h.sendJSONResponse(w, api.VersionResponse{
+		Version:   version.Version,
+		Commit:    version.Commit,
+		BuildTime: version.BuildTime,
+	}, http.StatusOK)
+}
+
+// HandleCacheStats handles requests to the /cache/stats endpoint
+func (h *StockHandler) HandleCacheStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	stats := h.stockService.CacheStats()
+	h.sendJSONResponse(w, api.CacheStatsResponse{
+		Hits:      stats.Hits,
+		Misses:    stats.Misses,
+		Evictions: stats.Evictions,
+		Items:     stats.Items,
+	}, http.StatusOK)
+}
+
+// HandleStatus handles requests to the /status endpoint, reporting the upstream
+// fetch history of every symbol fetched since startup
+func (h *StockHandler) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	statuses := h.stockService.FetchStatuses()
+	response := make(map[string]api.SymbolStatus, len(statuses))
+	for symbol, status := range statuses {
+		status := status // the fields are referenced beyond this iteration
+		symbolStatus := api.SymbolStatus{LastError: status.LastError, ErrorCount: status.ErrorCount}
+		if !status.LastSuccess.IsZero() {
+			symbolStatus.LastSuccess = &status.LastSuccess
+		}
+		if !status.LastErrorAt.IsZero() {
+			symbolStatus.LastErrorAt = &status.LastErrorAt
+		}
+		response[symbol] = symbolStatus
+	}
+
+	h.sendJSONResponse(w, response, http.StatusOK)
+}
+
+// HandleNotFound handles requests to paths that match no other route
+func (h *StockHandler) HandleNotFound(w http.ResponseWriter, r *http.Request) {
+	h.sendErrorResponse(w, fmt.Sprintf("no route for %s", r.URL.Path), http.StatusNotFound)
+}
+
+// isReady reports whether the handler has the configuration it needs to serve
+// requests. The mock provider serves canned data and needs no API key.
+func (h *StockHandler) isReady() bool {
+	if h.config == nil || h.stockService == nil {
+		return false
+	}
+	return h.config.APIKey != "" || h.config.Provider == config.ProviderMock
+}
+
+// statusForError maps a service error to the HTTP status code returned to the client
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, client.ErrRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, service.ErrSymbolNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, client.ErrInvalidSymbol):
+		return http.StatusNotFound
+	case errors.Is(err, client.ErrUpstreamUnavailable), errors.Is(err, client.ErrResponseTooLarge),
+		errors.Is(err, service.ErrStaleData):
+		return http.StatusBadGateway
+	case errors.Is(err, service.ErrCurrencyUnsupported), errors.Is(err, service.ErrSearchUnsupported),
+		errors.Is(err, service.ErrAdjustedUnsupported), errors.Is(err, service.ErrQuotesUnsupported),
+		errors.Is(err, client.ErrIntervalUnsupported):
+		return http.StatusNotImplemented
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// codeForError maps a service error to the error code returned to the client
+func codeForError(err error) api.ErrorCode {
+	switch {
+	case errors.Is(err, client.ErrRateLimited):
+		return api.ErrorCodeRateLimited
+	case errors.Is(err, service.ErrSymbolNotAllowed):
+		return api.ErrorCodeSymbolNotAllowed
+	case errors.Is(err, client.ErrInvalidSymbol):
+		return api.ErrorCodeInvalidSymbol
+	case errors.Is(err, service.ErrStaleData):
+		return api.ErrorCodeStaleData
+	default:
+		return codeForStatus(statusForError(err))
+	}
+}
+
+// codeForStatus returns the error code describing an HTTP error status
+func codeForStatus(status int) api.ErrorCode {
+	switch status {
+	case http.StatusBadRequest:
+		return api.ErrorCodeInvalidRequest
+	case http.StatusUnauthorized:
+		return api.ErrorCodeUnauthorized
+	case http.StatusForbidden:
+		return api.ErrorCodeSymbolNotAllowed
+	case http.StatusNotFound:
+		return api.ErrorCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return api.ErrorCodeMethodNotAllowed
+	case http.StatusTooManyRequests:
+		return api.ErrorCodeRateLimited
+	case http.StatusNotImplemented:
+		return api.ErrorCodeNotImplemented
+	case http.StatusBadGateway:
+		return api.ErrorCodeUpstreamError
+	case http.StatusServiceUnavailable:
+		return api.ErrorCodeUnavailable
+	default:
+		return api.ErrorCodeInternal
+	}
+}
+
+// buildQuery resolves the query parameters shared by single and multi-symbol requests
+func (h *StockHandler) buildQuery(r *http.Request) (service.Query, error) {
+	days, err := h.resolveDays(r)
+	if err != nil {
+		return service.Query{}, err
+	}
+
+	interval, err := resolveInterval(r)
+	if err != nil {
+		return service.Query{}, err
+	}
+
+	from, to, err := resolveDateRange(r)
+	if err != nil {
+		return service.Query{}, err
+	}
+
+	strict := false
+	if raw := r.URL.Query().Get("strict"); raw != "" {
+		if strict, err = strconv.ParseBool(raw); err != nil {
+			return service.Query{}, fmt.Errorf("invalid strict parameter: must be true or false")
+		}
+	}
+
+	adjusted := false
+	if raw := r.URL.Query().Get("adjusted"); raw != "" {
+		if adjusted, err = strconv.ParseBool(raw); err != nil {
+			return service.Query{}, fmt.Errorf("invalid adjusted parameter: must be true or false")
+		}
+		if adjusted && interval != client.IntervalDaily {
+			return service.Query{}, fmt.Errorf("adjusted parameter requires the daily interval, got %s", interval)
+		}
+	}
+
+	priceField, err := resolvePriceField(r)
+	if err != nil {
+		return service.Query{}, err
+	}
+
+	return service.Query{Days: days, Interval: interval, From: from, To: to, Strict: strict, Adjusted: adjusted, PriceField: priceField}, nil
+}
+
+// resolvePriceField returns the price_field query parameter. The close is the
+// default and is returned as empty so it shares cache entries with requests
+// that leave the parameter out.
+func resolvePriceField(r *http.Request) (models.PriceField, error) {
+	switch field := models.PriceField(r.URL.Query().Get("price_field")); field {
+	case "", models.PriceFieldClose:
+		return "", nil
+	case models.PriceFieldOpen, models.PriceFieldHigh, models.PriceFieldLow:
+		return field, nil
+	default:
+		return "", fmt.Errorf("invalid price_field %q: must be %s, %s, %s or %s",
+			field, models.PriceFieldOpen, models.PriceFieldHigh, models.PriceFieldLow, models.PriceFieldClose)
+	}
+}
+
+// resolveSymbol returns the symbol query parameter, falling back to the configured default
+func (h *StockHandler) resolveSymbol(r *http.Request) (string, error) {
+	query := r.URL.Query()
+	if !query.Has("symbol") {
+		return h.config.Symbol, nil
+	}
+
+	symbol := config.NormalizeSymbol(query.Get("symbol"))
+	if symbol == "" {
+		return "", fmt.Errorf("symbol parameter must not be empty")
+	}
+	if err := config.ValidateSymbol(symbol); err != nil {
+		return "", err
+	}
+
+	return symbol, nil
+}
+
+// resolveDays returns the days query parameter capped at maxDays, falling back to the configured default
+func (h *StockHandler) resolveDays(r *http.Request) (int, error) {
+	query := r.URL.Query()
+	if !query.Has("days") {
+		return h.config.NDays, nil
+	}
+
+	days, err := strconv.Atoi(query.Get("days"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid days parameter: %w", err)
+	}
+	if days <= 0 {
+		return 0, fmt.Errorf("days parameter must be positive, got %d", days)
+	}
+	if days > maxDays {
+		days = maxDays
+	}
+
+	return days, nil
+}
+
+// resolveInterval returns the interval query parameter, defaulting to daily
+func resolveInterval(r *http.Request) (client.Interval, error) {
+	query := r.URL.Query()
+	if !query.Has("interval") {
+		return client.IntervalDaily, nil
+	}
+	return client.ParseInterval(query.Get("interval"))
+}
+
+// resolveDateRange returns the optional inclusive from and to date query parameters
+func resolveDateRange(r *http.Request) (time.Time, time.Time, error) {
+	query := r.URL.Query()
+
+	var from, to time.Time
+	var err error
+	if query.Has("from") {
+		if from, err = time.Parse(dateLayout, query.Get("from")); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from parameter: must be a date in YYYY-MM-DD format")
+		}
+	}
+	if query.Has("to") {
+		if to, err = time.Parse(dateLayout, query.Get("to")); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to parameter: must be a date in YYYY-MM-DD format")
+		}
+	}
+
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from date %s must not be after to date %s", from.Format(dateLayout), to.Format(dateLayout))
+	}
+
+	return from, to, nil
+}
+
+// resolveThreshold reads the required threshold query parameter, a positive percentage
+func resolveThreshold(r *http.Request) (float64, error) {
+	query := r.URL.Query()
+	if !query.Has("threshold") {
+		return 0, fmt.Errorf("threshold parameter is required")
+	}
+
+	threshold, err := strconv.ParseFloat(query.Get("threshold"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid threshold parameter: %w", err)
+	}
+	if threshold <= 0 || math.IsInf(threshold, 0) || math.IsNaN(threshold) {
+		return 0, fmt.Errorf("threshold parameter must be a positive number, got %s", query.Get("threshold"))
+	}
+
+	return threshold, nil
+}
+
+// resolveCurrency returns the currency query parameter in upper case, or an empty string when unset
+func resolveCurrency(r *http.Request) (string, error) {
+	query := r.URL.Query()
+	if !query.Has("currency") {
+		return "", nil
+	}
+
+	currency := strings.ToUpper(query.Get("currency"))
+	if !currencyPattern.MatchString(currency) {
+		return "", fmt.Errorf("invalid currency %q: must be a three-letter ISO 4217 code such as EUR", query.Get("currency"))
+	}
+
+	return currency, nil
+}
+
+// parseSymbols splits and validates a comma-separated symbols parameter
+func parseSymbols(raw string) ([]string, error) {
+	return validateSymbols(strings.Split(raw, ","))
+}
+
+// parseIntervals parses a comma-separated list of intervals, dropping duplicates
+func parseIntervals(raw string) ([]client.Interval, error) {
+	var intervals []client.Interval
+	seen := make(map[client.Interval]bool)
+	for _, value := range strings.Split(raw, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		interval, err := client.ParseInterval(value)
+		if err != nil {
+			return nil, err
+		}
+		if seen[interval] {
+			continue
+		}
+		seen[interval] = true
+		intervals = append(intervals, interval)
+	}
+
+	if len(intervals) == 0 {
+		return nil, fmt.Errorf("intervals parameter must contain at least one interval")
+	}
+	if len(intervals) > maxIntervals {
+		return nil, fmt.Errorf("intervals parameter must not contain more than %d intervals, got %d", maxIntervals, len(intervals))
+	}
+
+	return intervals, nil
+}
+
+// validateSymbols normalizes and validates a list of symbols, dropping blanks
+// and duplicates
+func validateSymbols(raw []string) ([]string, error) {
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, symbol := range raw {
+		symbol = config.NormalizeSymbol(symbol)
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		if err := config.ValidateSymbol(symbol); err != nil {
+			return nil, err
+		}
+		seen[symbol] = true
+		symbols = append(symbols, symbol)
+	}
+
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("symbols parameter must contain at least one symbol")
+	}
+	if len(symbols) > maxSymbols {
+		return nil, fmt.Errorf("symbols parameter must not contain more than %d symbols, got %d", maxSymbols, len(symbols))
+	}
+
+	return symbols, nil
+}
+
+// sendJSONResponse sends a JSON response to the client with the given status code
+func (h *StockHandler) sendJSONResponse(w http.ResponseWriter, data interface{}, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(statusCode)
 
 	if err := json.NewEncoder(w).Encode(data); err != nil {
-		log.Printf("Error encoding JSON response: %v", err)
+		h.logger.Error("error encoding JSON response", "error", err)
 		http.Error(w, "Error encoding response", http.StatusInternalServerError)
 	}
 }
 
-// sendErrorResponse sends an error response to the client
+// sendMethodNotAllowed sends a 405 error response listing the allowed methods in the Allow header
+func (h *StockHandler) sendMethodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	h.sendErrorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+}
+
+// sendErrorResponse sends an error response to the client with the code of its status
 func (h *StockHandler) sendErrorResponse(w http.ResponseWriter, message string, statusCode int) {
+	h.writeError(w, api.ErrorResponse{Code: codeForStatus(statusCode), Error: message}, statusCode)
+}
+
+// sendServiceError sends a service error to the client with the code of its type
+func (h *StockHandler) sendServiceError(w http.ResponseWriter, err error, statusCode int) {
+	h.writeError(w, api.ErrorResponse{Code: codeForError(err), Error: err.Error()}, statusCode)
+}
+
+// writeError writes response as the body of an error with the given status
+func (h *StockHandler) writeError(w http.ResponseWriter, response api.ErrorResponse, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
-	response := api.ErrorResponse{Error: message}
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		log.Printf("Error encoding error response: %v", err)
+		h.logger.Error("error encoding error response", "error", err)
 		http.Error(w, "Error encoding response", http.StatusInternalServerError)
 	}
 }
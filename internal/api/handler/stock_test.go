@@ -0,0 +1,647 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/api"
+	"github.com/saedabdu/stockticker/internal/cache"
+	"github.com/saedabdu/stockticker/internal/client"
+	"github.com/saedabdu/stockticker/internal/config"
+	"github.com/saedabdu/stockticker/internal/service"
+	"github.com/saedabdu/stockticker/internal/version"
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+func TestErrorResponsesAreJSON(t *testing.T) {
+	h := NewStockHandler(&config.Config{}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		method     string
+		path       string
+		wantStatus int
+		wantCode   api.ErrorCode
+	}{
+		{
+			name:       "unknown route",
+			handler:    h.HandleNotFound,
+			method:     http.MethodGet,
+			path:       "/unknown",
+			wantStatus: http.StatusNotFound,
+			wantCode:   api.ErrorCodeNotFound,
+		},
+		{
+			name:       "wrong method",
+			handler:    h.HandleHealth,
+			method:     http.MethodPost,
+			path:       "/health",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantCode:   api.ErrorCodeMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("expected Content-Type application/json, got %q", ct)
+			}
+			if tt.wantStatus == http.StatusMethodNotAllowed && rec.Header().Get("Allow") != http.MethodGet {
+				t.Errorf("expected Allow GET, got %q", rec.Header().Get("Allow"))
+			}
+			var response api.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&response); err != nil || response.Error == "" {
+				t.Errorf("expected a JSON error body, got %v (%v)", response, err)
+			}
+			if response.Code != tt.wantCode {
+				t.Errorf("expected code %s, got %q", tt.wantCode, response.Code)
+			}
+		})
+	}
+}
+
+func TestStatusForError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "rate limited", err: fmt.Errorf("%w: try later", client.ErrRateLimited), want: http.StatusTooManyRequests},
+		{name: "invalid symbol", err: fmt.Errorf("%w: no data", client.ErrInvalidSymbol), want: http.StatusNotFound},
+		{name: "upstream unavailable", err: fmt.Errorf("%w: status 503", client.ErrUpstreamUnavailable), want: http.StatusBadGateway},
+		{name: "unsupported", err: service.ErrAdjustedUnsupported, want: http.StatusNotImplemented},
+		{name: "unsupported interval", err: fmt.Errorf("%w: weekly", client.ErrIntervalUnsupported), want: http.StatusNotImplemented},
+		{name: "other", err: errors.New("boom"), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statusForError(tt.err); got != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCodeForError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want api.ErrorCode
+	}{
+		{name: "rate limited", err: fmt.Errorf("%w: try later", client.ErrRateLimited), want: api.ErrorCodeRateLimited},
+		{name: "invalid symbol", err: &client.InvalidSymbolError{Symbol: "XYZ", Message: "no data"}, want: api.ErrorCodeInvalidSymbol},
+		{name: "not allowed", err: fmt.Errorf("%w: XYZ", service.ErrSymbolNotAllowed), want: api.ErrorCodeSymbolNotAllowed},
+		{name: "upstream unavailable", err: fmt.Errorf("%w: status 503", client.ErrUpstreamUnavailable), want: api.ErrorCodeUpstreamError},
+		{name: "stale", err: service.ErrStaleData, want: api.ErrorCodeStaleData},
+		{name: "unsupported", err: service.ErrAdjustedUnsupported, want: api.ErrorCodeNotImplemented},
+		{name: "unsupported interval", err: client.ErrIntervalUnsupported, want: api.ErrorCodeNotImplemented},
+		{name: "other", err: errors.New("boom"), want: api.ErrorCodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := codeForError(tt.err); got != tt.want {
+				t.Errorf("expected code %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHandleStocksDeleteInvalidatesCache(t *testing.T) {
+	cfg := &config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewStockHandler(cfg, service.New(cfg, quoteProvider{}, cache.New(0), logger), logger)
+
+	for _, target := range []string{"/stocks?symbol=AAPL", "/stocks?symbol=AAPL&days=30", "/stocks?symbol=MSFT"} {
+		rec := httptest.NewRecorder()
+		h.HandleStocks(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected status 200, got %d: %s", target, rec.Code, rec.Body.String())
+		}
+	}
+	rec := httptest.NewRecorder()
+	h.HandleQuote(rec, httptest.NewRequest(http.MethodGet, "/quote?symbols=AAPL", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /quote: expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleStocks(rec, httptest.NewRequest(http.MethodDelete, "/stocks?symbol=AAPL", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	// Every AAPL window and the AAPL quote are removed and MSFT is left cached
+	if got := h.stockService.CacheStats().Items; got != 1 {
+		t.Errorf("expected 1 cached item after invalidating AAPL, got %d", got)
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleStocks(rec, httptest.NewRequest(http.MethodDelete, "/stocks?symbol=TOOLONG", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid symbol, got %d", rec.Code)
+	}
+}
+
+// movingProvider answers every symbol with two closes, 100 then 106
+type movingProvider struct{}
+
+func (movingProvider) GetStockData(ctx context.Context, symbol string, days int, interval client.Interval) (*models.AlphaVantageResponse, error) {
+	return &models.AlphaVantageResponse{
+		TimeSeries: map[string]models.DailyPrice{
+			"2023-01-03": {Open: "100", High: "100", Low: "100", Close: "100", Volume: "1"},
+			"2023-01-04": {Open: "106", High: "106", Low: "106", Close: "106", Volume: "1"},
+		},
+	}, nil
+}
+
+func TestHandleAlert(t *testing.T) {
+	cfg := &config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewStockHandler(cfg, service.New(cfg, movingProvider{}, cache.New(0), logger), logger)
+
+	tests := []struct {
+		name          string
+		target        string
+		wantStatus    int
+		wantTriggered bool
+	}{
+		{name: "triggered", target: "/stocks/alert?symbol=AAPL&threshold=5", wantStatus: http.StatusOK, wantTriggered: true},
+		{name: "not triggered", target: "/stocks/alert?symbol=AAPL&threshold=6.5", wantStatus: http.StatusOK},
+		{name: "missing threshold", target: "/stocks/alert?symbol=AAPL", wantStatus: http.StatusBadRequest},
+		{name: "zero threshold", target: "/stocks/alert?symbol=AAPL&threshold=0", wantStatus: http.StatusBadRequest},
+		{name: "negative threshold", target: "/stocks/alert?symbol=AAPL&threshold=-5", wantStatus: http.StatusBadRequest},
+		{name: "non-numeric threshold", target: "/stocks/alert?symbol=AAPL&threshold=high", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.HandleAlert(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got api.AlertResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if got.Date != "2023-01-04" || got.ChangePercent != 6 || got.Triggered != tt.wantTriggered {
+				t.Errorf("expected 6%% change on 2023-01-04 with triggered %v, got %+v", tt.wantTriggered, got)
+			}
+		})
+	}
+
+	t.Run("single price", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newTestHandler(cfg).HandleAlert(rec, httptest.NewRequest(http.MethodGet, "/stocks/alert?symbol=AAPL&threshold=5", nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("expected status 404 without a previous close, got %d", rec.Code)
+		}
+	})
+}
+
+func TestHandlersNormalizeSymbols(t *testing.T) {
+	h := newTestHandler(&config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}})
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		target  string
+		want    string
+	}{
+		{name: "lowercase symbol", handler: h.HandleStocks, target: "/stocks?symbol=aapl", want: `"symbol":"AAPL"`},
+		{name: "padded symbol", handler: h.HandleStocks, target: "/stocks?symbol=%20AAPL%20", want: `"symbol":"AAPL"`},
+		{name: "lowercase latest", handler: h.HandleLatest, target: "/stocks/latest?symbol=msft", want: `"symbol":"MSFT"`},
+		{name: "mixed symbols", handler: h.HandleStocks, target: "/stocks?symbols=aapl,%20AAPL,Msft", want: `"symbol":"MSFT"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("expected %s in the response, got %s", tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	// Duplicates are dropped after normalization
+	rec := httptest.NewRecorder()
+	h.HandleStocks(rec, httptest.NewRequest(http.MethodGet, "/stocks?symbols=aapl,%20AAPL,Msft", nil))
+	var results []api.SymbolResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil || len(results) != 2 {
+		t.Errorf("expected 2 results, got %s (%v)", rec.Body.String(), err)
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleStocks(rec, httptest.NewRequest(http.MethodGet, "/stocks?symbol=%20", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for a blank symbol, got %d", rec.Code)
+	}
+}
+
+func TestHandleAverage(t *testing.T) {
+	h := newTestHandler(&config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}})
+
+	rec := httptest.NewRecorder()
+	h.HandleAverage(rec, httptest.NewRequest(http.MethodGet, "/stocks/average?symbol=AAPL&days=30", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(got) != 2 || string(got["symbol"]) != `"AAPL"` || got["average"] == nil {
+		t.Errorf("expected only symbol and average, got %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleAverage(rec, httptest.NewRequest(http.MethodGet, "/stocks/average?symbol=TOOLONG", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid symbol, got %d", rec.Code)
+	}
+}
+
+// fieldsProvider returns a single entry whose prices differ by field
+type fieldsProvider struct{}
+
+func (fieldsProvider) GetStockData(ctx context.Context, symbol string, days int, interval client.Interval) (*models.AlphaVantageResponse, error) {
+	return &models.AlphaVantageResponse{
+		TimeSeries: map[string]models.DailyPrice{
+			"2023-01-03": {Open: "101", High: "105", Low: "99", Close: "102", Volume: "1"},
+		},
+	}, nil
+}
+
+func TestHandleAveragePriceField(t *testing.T) {
+	cfg := &config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewStockHandler(cfg, service.New(cfg, fieldsProvider{}, cache.New(0), logger), logger)
+
+	tests := []struct {
+		target string
+		want   api.AverageResponse
+	}{
+		{target: "/stocks/average?symbol=AAPL", want: api.AverageResponse{Symbol: "AAPL", Average: 102}},
+		{target: "/stocks/average?symbol=AAPL&price_field=high", want: api.AverageResponse{Symbol: "AAPL", Average: 105, PriceField: models.PriceFieldHigh}},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.HandleAverage(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", tt.target, rec.Code, rec.Body.String())
+		}
+		var got api.AverageResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("invalid JSON: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.target, tt.want, got)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.HandleAverage(rec, httptest.NewRequest(http.MethodGet, "/stocks/average?symbol=AAPL&price_field=volume", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid price_field, got %d", rec.Code)
+	}
+}
+
+func TestHandleExtremes(t *testing.T) {
+	cfg := &config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewStockHandler(cfg, service.New(cfg, movingProvider{}, cache.New(0), logger), logger)
+
+	rec := httptest.NewRecorder()
+	h.HandleExtremes(rec, httptest.NewRequest(http.MethodGet, "/stocks/extremes?symbol=AAPL&days=252", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var got api.ExtremesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	want := api.ExtremesResponse{
+		Symbol: "AAPL",
+		High:   api.PricePoint{Date: "2023-01-04", Close: 106},
+		Low:    api.PricePoint{Date: "2023-01-03", Close: 100},
+	}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleExtremes(rec, httptest.NewRequest(http.MethodGet, "/stocks/extremes?symbol=AAPL&days=0", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid days, got %d", rec.Code)
+	}
+}
+
+// quoteProvider is a stubProvider that also returns quotes, failing for FAIL
+type quoteProvider struct{ stubProvider }
+
+func (quoteProvider) GetGlobalQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	if symbol == "FAIL" {
+		return nil, client.ErrInvalidSymbol
+	}
+	return &models.Quote{Symbol: symbol, Price: 101.5, Change: 1.5, ChangePercent: 1.5, LatestTradingDay: "2023-01-04"}, nil
+}
+
+func TestHandleQuote(t *testing.T) {
+	cfg := &config.Config{CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewStockHandler(cfg, service.New(cfg, quoteProvider{}, cache.New(0), logger), logger)
+
+	rec := httptest.NewRecorder()
+	h.HandleQuote(rec, httptest.NewRequest(http.MethodGet, "/quote?symbols=AAPL,FAIL", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var got []api.QuoteResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 quotes, got %d", len(got))
+	}
+	want := api.QuoteData{Price: 101.5, Change: 1.5, ChangePercent: 1.5, LatestTradingDay: "2023-01-04"}
+	if got[0].Symbol != "AAPL" || got[0].QuoteData == nil || *got[0].QuoteData != want {
+		t.Errorf("expected AAPL quote %+v, got %+v", want, got[0])
+	}
+	if got[1].Symbol != "FAIL" || got[1].Error == "" || got[1].QuoteData != nil {
+		t.Errorf("expected an error for FAIL, got %+v", got[1])
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleQuote(rec, httptest.NewRequest(http.MethodGet, "/quote", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 without symbols, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	newTestHandler(cfg).HandleQuote(rec, httptest.NewRequest(http.MethodGet, "/quote?symbols=AAPL", nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501 from a provider without quotes, got %d", rec.Code)
+	}
+}
+
+func TestHandleStocksPriceField(t *testing.T) {
+	h := newTestHandler(&config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}})
+
+	rec := httptest.NewRecorder()
+	h.HandleStocks(rec, httptest.NewRequest(http.MethodGet, "/stocks?symbol=AAPL&price_field=open", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var got api.StockResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.PriceField != models.PriceFieldOpen {
+		t.Errorf("expected price_field open, got %q", got.PriceField)
+	}
+
+	for _, field := range []string{"adjusted", "Open"} {
+		rec := httptest.NewRecorder()
+		h.HandleStocks(rec, httptest.NewRequest(http.MethodGet, "/stocks?symbol=AAPL&price_field="+field, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected status 400 for price_field %q, got %d", field, rec.Code)
+		}
+	}
+}
+
+func TestHandleStocksAllowedSymbols(t *testing.T) {
+	h := newTestHandler(&config.Config{NDays: 7, AllowedSymbols: []string{"AAPL"}, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}})
+
+	rec := httptest.NewRecorder()
+	h.HandleStocks(rec, httptest.NewRequest(http.MethodGet, "/stocks?symbol=AAPL", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200 for an allowed symbol, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleStocks(rec, httptest.NewRequest(http.MethodGet, "/stocks?symbol=MSFT", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status 403 for a symbol outside the allowlist, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleCorrelation(t *testing.T) {
+	cfg := &config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewStockHandler(cfg, service.New(cfg, movingProvider{}, cache.New(0), logger), logger)
+
+	rec := httptest.NewRecorder()
+	h.HandleCorrelation(rec, httptest.NewRequest(http.MethodGet, "/stocks/correlation?a=AAPL&b=MSFT&days=90", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var got api.CorrelationResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	want := api.CorrelationResponse{A: "AAPL", B: "MSFT", Points: 2, Correlation: 1}
+	if got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	tests := []struct {
+		name       string
+		handler    *StockHandler
+		target     string
+		wantStatus int
+	}{
+		{name: "normalized symbols", handler: h, target: "/stocks/correlation?a=aapl&b=%20msft&days=90", wantStatus: http.StatusOK},
+		{name: "missing b", handler: h, target: "/stocks/correlation?a=AAPL", wantStatus: http.StatusBadRequest},
+		{name: "blank b", handler: h, target: "/stocks/correlation?a=AAPL&b=%20", wantStatus: http.StatusBadRequest},
+		{name: "invalid symbol", handler: h, target: "/stocks/correlation?a=AAPL&b=TOOLONG", wantStatus: http.StatusBadRequest},
+		{name: "unknown symbol", handler: newTestHandler(cfg), target: "/stocks/correlation?a=AAPL&b=FAIL", wantStatus: http.StatusNotFound},
+		{name: "single aligned date", handler: newTestHandler(cfg), target: "/stocks/correlation?a=AAPL&b=MSFT", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler.HandleCorrelation(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+// unreachableProvider fails every request as if the provider were down
+type unreachableProvider struct{}
+
+func (unreachableProvider) GetStockData(ctx context.Context, symbol string, days int, interval client.Interval) (*models.AlphaVantageResponse, error) {
+	return nil, client.ErrUpstreamUnavailable
+}
+
+func TestHandleHealthDeep(t *testing.T) {
+	cfg := &config.Config{Symbol: "IBM", ProviderConfig: config.ProviderConfig{APIKey: "key"}, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name       string
+		provider   client.StockProvider
+		target     string
+		wantStatus int
+		want       api.HealthResponse
+	}{
+		{name: "shallow", provider: unreachableProvider{}, target: "/health", wantStatus: http.StatusOK, want: api.HealthResponse{Status: "ok"}},
+		{name: "reachable", provider: stubProvider{}, target: "/health?deep=true", wantStatus: http.StatusOK, want: api.HealthResponse{Status: "ok", Upstream: "ok"}},
+		{
+			name:       "unreachable",
+			provider:   unreachableProvider{},
+			target:     "/health?deep=true",
+			wantStatus: http.StatusServiceUnavailable,
+			want:       api.HealthResponse{Status: "degraded", Upstream: "unreachable", Error: client.ErrUpstreamUnavailable.Error()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewStockHandler(cfg, service.New(cfg, tt.provider, cache.New(0), logger), logger)
+			rec := httptest.NewRecorder()
+			h.HandleHealth(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			var got api.HealthResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	newTestHandler(cfg).HandleHealth(rec, httptest.NewRequest(http.MethodGet, "/health?deep=maybe", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for an invalid deep parameter, got %d", rec.Code)
+	}
+}
+
+func TestHandleHealthMockProvider(t *testing.T) {
+	cfg := &config.Config{Symbol: "IBM", ProviderConfig: config.ProviderConfig{Provider: config.ProviderMock}, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewStockHandler(cfg, service.New(cfg, client.NewMock(), cache.New(0), logger), logger)
+
+	for _, target := range []string{"/health", "/health?deep=true"} {
+		rec := httptest.NewRecorder()
+		h.HandleHealth(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200 without an API key for the mock provider, got %d: %s", target, rec.Code, rec.Body.String())
+		}
+	}
+
+	cfg = &config.Config{ProviderConfig: config.ProviderConfig{Provider: config.ProviderAlphaVantage}}
+	rec := httptest.NewRecorder()
+	newTestHandler(cfg).HandleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 without an API key for Alpha Vantage, got %d", rec.Code)
+	}
+}
+
+func TestHandleStocksMockProviderInterval(t *testing.T) {
+	cfg := &config.Config{Symbol: "IBM", NDays: 7, ProviderConfig: config.ProviderConfig{Provider: config.ProviderMock}, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewStockHandler(cfg, service.New(cfg, client.NewMock(), cache.New(0), logger), logger)
+
+	rec := httptest.NewRecorder()
+	h.HandleStocks(rec, httptest.NewRequest(http.MethodGet, "/stocks?interval=weekly", nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Errorf("expected status 501 for a weekly interval from the mock provider, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleVersion(t *testing.T) {
+	h := newTestHandler(&config.Config{})
+
+	rec := httptest.NewRecorder()
+	h.HandleVersion(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var got api.VersionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.Version != version.Version {
+		t.Errorf("expected version %q, got %q", version.Version, got.Version)
+	}
+}
+
+func TestHandleStocksIntervals(t *testing.T) {
+	h := newTestHandler(&config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}})
+
+	rec := httptest.NewRecorder()
+	h.HandleStocks(rec, httptest.NewRequest(http.MethodGet, "/stocks?symbol=AAPL&intervals=daily,weekly,daily", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var got api.IntervalsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if got.Symbol != "AAPL" || len(got.Intervals) != 2 {
+		t.Fatalf("expected AAPL with daily and weekly, got %+v", got)
+	}
+	for _, interval := range []string{"daily", "weekly"} {
+		if entry := got.Intervals[interval]; entry.StockResponse == nil || entry.Error != "" {
+			t.Errorf("expected %s data, got %+v", interval, entry)
+		}
+	}
+	// Each interval is cached under its own key
+	if items := h.stockService.CacheStats().Items; items != 2 {
+		t.Errorf("expected 2 cached entries, got %d", items)
+	}
+
+	for _, target := range []string{
+		"/stocks?symbol=AAPL&intervals=daily,hourly",
+		"/stocks?symbol=AAPL&intervals=",
+		"/stocks?symbol=AAPL&intervals=daily,weekly,monthly,1min,5min",
+		"/stocks?symbol=AAPL&intervals=daily&interval=weekly",
+		"/stocks?symbols=AAPL,MSFT&intervals=daily",
+		"/stocks?symbol=AAPL&intervals=daily,weekly&adjusted=true",
+	} {
+		rec := httptest.NewRecorder()
+		h.HandleStocks(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: expected status 400, got %d", target, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/api"
+	"github.com/saedabdu/stockticker/internal/service"
+)
+
+// HandleStream handles requests to the /stocks/stream endpoint, pushing the stock
+// data for a symbol as Server-Sent Events once per CacheTTL until the client
+// disconnects. It accepts the same parameters as a single-symbol /stocks request.
+func (h *StockHandler) HandleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	query, err := h.buildQuery(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts, err := parseResponseOptions(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	currency, err := resolveCurrency(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	symbol, err := h.resolveSymbol(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query.Symbol = symbol
+	if !r.URL.Query().Has("days") {
+		query.Days = h.config.NDaysFor(symbol)
+	}
+
+	// The stream outlives the server's write timeout; writers that cannot lift it are left as they are
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	ticker := time.NewTicker(h.config.CacheTTL)
+	defer ticker.Stop()
+
+	for {
+		if err := h.sendStockEvent(w, r, query, opts, currency); err != nil {
+			// The client has gone away
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendStockEvent writes the current stock data for query as a "stock" event, or
+// an "error" event when it cannot be retrieved. The returned error is only set
+// when writing fails.
+func (h *StockHandler) sendStockEvent(w http.ResponseWriter, r *http.Request, query service.Query, opts responseOptions, currency string) error {
+	resp, err := h.currentStock(r.Context(), query, opts, currency)
+	if err != nil {
+		return writeEvent(w, "error", api.ErrorResponse{Code: codeForError(err), Error: err.Error()})
+	}
+	return writeEvent(w, "stock", resp)
+}
+
+// currentStock fetches the stock data for query for a push update, logging any
+// failure since the client only sees it as a message on the open connection
+func (h *StockHandler) currentStock(ctx context.Context, query service.Query, opts responseOptions, currency string) (api.StockResponse, error) {
+	stockData, err := h.stockService.GetStockData(ctx, query)
+	if err == nil {
+		stockData, err = h.stockService.ConvertCurrency(ctx, stockData, currency)
+	}
+	if err != nil {
+		h.logger.ErrorContext(ctx, "error getting stock data",
+			"symbol", query.Symbol, "days", query.Days, "status", statusForError(err), "error", err)
+		return api.StockResponse{}, err
+	}
+
+	return toStockResponse(opts.apply(stockData)), nil
+}
+
+// writeEvent writes data as a Server-Sent Event of the given type. JSON encoding
+// never contains raw newlines, so the data fits on a single data line.
+func writeEvent(w http.ResponseWriter, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
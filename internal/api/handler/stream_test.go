@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/config"
+)
+
+func TestHandleStream(t *testing.T) {
+	h := newTestHandler(&config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: 10 * time.Millisecond}})
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantEvent  string
+	}{
+		{name: "stock events", target: "/stocks/stream?symbol=AAPL", wantStatus: http.StatusOK, wantEvent: "event: stock\n"},
+		{name: "error events", target: "/stocks/stream?symbol=FAIL", wantStatus: http.StatusOK, wantEvent: "event: error\n"},
+		{name: "invalid days", target: "/stocks/stream?symbol=AAPL&days=0", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			rec := httptest.NewRecorder()
+			h.HandleStream(rec, httptest.NewRequest(http.MethodGet, tt.target, nil).WithContext(ctx))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantEvent == "" {
+				return
+			}
+			if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+				t.Errorf("expected Content-Type text/event-stream, got %q", got)
+			}
+			if !rec.Flushed {
+				t.Error("expected events to be flushed")
+			}
+			// The first event is sent at once and at least one more on the ticker
+			if n := strings.Count(rec.Body.String(), tt.wantEvent); n < 2 {
+				t.Errorf("expected repeated %q events, got %d in %q", strings.TrimSpace(tt.wantEvent), n, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("wrong method", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.HandleStream(rec, httptest.NewRequest(http.MethodPost, "/stocks/stream?symbol=AAPL", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+		}
+	})
+}
@@ -0,0 +1,274 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/saedabdu/stockticker/internal/api"
+	"github.com/saedabdu/stockticker/internal/service"
+)
+
+const (
+	// wsWriteWait is how long a single message write may take
+	wsWriteWait = 10 * time.Second
+	// wsPongWait is how long the client may stay silent before it is considered gone
+	wsPongWait = 60 * time.Second
+	// wsPingPeriod is how often the server pings the client; it must be shorter than wsPongWait
+	wsPingPeriod = wsPongWait * 9 / 10
+	// wsMaxMessageSize is the largest message accepted from a client
+	wsMaxMessageSize = 4096
+)
+
+// Message types sent to /ws clients
+const (
+	wsMessageStock = "stock"
+	wsMessageError = "error"
+)
+
+// Subscription actions accepted from /ws clients
+const (
+	wsActionSubscribe   = "subscribe"
+	wsActionUnsubscribe = "unsubscribe"
+)
+
+// HandleWebSocket handles requests to the /ws endpoint. After the upgrade the
+// client sends subscription messages and receives the stock data for each
+// subscribed symbol as soon as it subscribes and then once per CacheTTL. The
+// query parameters of the upgrade request, such as days or currency, apply to
+// every subscription on the connection.
+func (h *StockHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.sendMethodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	query, err := h.buildQuery(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts, err := parseResponseOptions(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	currency, err := resolveCurrency(r)
+	if err != nil {
+		h.sendErrorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	upgrader := websocket.Upgrader{CheckOrigin: h.checkOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already sent an error response
+		h.logger.WarnContext(r.Context(), "websocket upgrade failed", "error", err)
+		return
+	}
+	defer conn.Close()
+
+	// The request context is not cancelled when a hijacked client disconnects,
+	// so the reader cancels it once the connection fails
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	messages := make(chan []byte)
+	go readWebSocket(ctx, cancel, conn, messages)
+
+	s := &wsSession{
+		handler:  h,
+		conn:     conn,
+		query:    query,
+		opts:     opts,
+		currency: currency,
+		useDays:  r.URL.Query().Has("days"),
+		symbols:  make(map[string]bool),
+	}
+	s.run(ctx, messages)
+}
+
+// checkOrigin accepts same-origin upgrades and those from the CORS allowed origins
+func (h *StockHandler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range h.config.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return sameOrigin(r, origin)
+}
+
+// sameOrigin reports whether origin names the host the request was sent to
+func sameOrigin(r *http.Request, origin string) bool {
+	for _, scheme := range []string{"http://", "https://"} {
+		if origin == scheme+r.Host {
+			return true
+		}
+	}
+	return false
+}
+
+// readWebSocket passes each message from the client to messages until the
+// connection fails or the client goes quiet, then calls cancel
+func readWebSocket(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, messages chan<- []byte) {
+	defer cancel()
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		// Any message shows the client is still there
+		_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		select {
+		case messages <- data:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// wsSession is the state of one /ws connection. Only run's goroutine touches it,
+// and it is the connection's only writer.
+type wsSession struct {
+	handler  *StockHandler
+	conn     *websocket.Conn
+	query    service.Query
+	opts     responseOptions
+	currency string
+	// useDays is set when the client chose days, overriding the per-symbol default
+	useDays bool
+	symbols map[string]bool
+}
+
+// run serves the session until ctx is cancelled or a write fails
+func (s *wsSession) run(ctx context.Context, messages <-chan []byte) {
+	refresh := time.NewTicker(s.handler.config.CacheTTL)
+	defer refresh.Stop()
+	ping := time.NewTicker(wsPingPeriod)
+	defer ping.Stop()
+
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return
+		case data := <-messages:
+			var added []string
+			added, err = s.apply(data)
+			if err != nil {
+				err = s.write(api.StreamMessage{Type: wsMessageError, Error: err.Error()})
+			} else {
+				err = s.push(ctx, added)
+			}
+		case <-refresh.C:
+			err = s.push(ctx, s.subscribed())
+		case <-ping.C:
+			err = s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// apply updates the subscription set from a client message and returns the
+// symbols it added
+func (s *wsSession) apply(data []byte) ([]string, error) {
+	var sub api.Subscription
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+
+	symbols, err := validateSymbols(sub.Symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	switch sub.Action {
+	case wsActionSubscribe:
+		var added []string
+		for _, symbol := range symbols {
+			if !s.symbols[symbol] {
+				added = append(added, symbol)
+			}
+		}
+		if len(s.symbols)+len(added) > maxSymbols {
+			return nil, fmt.Errorf("cannot subscribe to more than %d symbols", maxSymbols)
+		}
+		for _, symbol := range added {
+			s.symbols[symbol] = true
+		}
+		return added, nil
+	case wsActionUnsubscribe:
+		for _, symbol := range symbols {
+			delete(s.symbols, symbol)
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("invalid action value: %q, must be %s or %s", sub.Action, wsActionSubscribe, wsActionUnsubscribe)
+	}
+}
+
+// subscribed returns the subscribed symbols in a stable order
+func (s *wsSession) subscribed() []string {
+	symbols := make([]string, 0, len(s.symbols))
+	for symbol := range s.symbols {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// push sends the current stock data for each symbol, or an error message for
+// symbols that could not be retrieved
+func (s *wsSession) push(ctx context.Context, symbols []string) error {
+	for _, symbol := range symbols {
+		query := s.query
+		query.Symbol = symbol
+		if !s.useDays {
+			query.Days = s.handler.config.NDaysFor(symbol)
+		}
+
+		msg := api.StreamMessage{Type: wsMessageStock, Symbol: symbol}
+		resp, err := s.handler.currentStock(ctx, query, s.opts, s.currency)
+		if err != nil {
+			msg.Type = wsMessageError
+			msg.Error = err.Error()
+		} else {
+			msg.Data = &resp
+		}
+
+		if err := s.write(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write sends msg as a JSON text message
+func (s *wsSession) write(msg api.StreamMessage) error {
+	if err := s.conn.SetWriteDeadline(time.Now().Add(wsWriteWait)); err != nil {
+		return err
+	}
+	return s.conn.WriteJSON(msg)
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/saedabdu/stockticker/internal/api"
+	"github.com/saedabdu/stockticker/internal/config"
+)
+
+func TestHandleWebSocket(t *testing.T) {
+	h := newTestHandler(&config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Hour}})
+	server := httptest.NewServer(http.HandlerFunc(h.HandleWebSocket))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	send := func(msg string) {
+		t.Helper()
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	receive := func() api.StreamMessage {
+		t.Helper()
+		var msg api.StreamMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		return msg
+	}
+
+	send(`{"action": "subscribe", "symbols": ["AAPL", "FAIL"]}`)
+	if msg := receive(); msg.Type != wsMessageStock || msg.Symbol != "AAPL" || msg.Data == nil || msg.Data.Symbol != "AAPL" {
+		t.Errorf("expected AAPL stock message, got %+v", msg)
+	}
+	if msg := receive(); msg.Type != wsMessageError || msg.Symbol != "FAIL" || msg.Error == "" {
+		t.Errorf("expected FAIL error message, got %+v", msg)
+	}
+
+	// Symbols already subscribed are not sent again
+	send(`{"action": "subscribe", "symbols": ["AAPL", "MSFT"]}`)
+	if msg := receive(); msg.Symbol != "MSFT" {
+		t.Errorf("expected only MSFT to be sent, got %+v", msg)
+	}
+
+	// Symbols are trimmed and uppercased, so these match the AAPL subscription and add IBM
+	send(`{"action": "subscribe", "symbols": ["aapl", " ibm "]}`)
+	if msg := receive(); msg.Type != wsMessageStock || msg.Symbol != "IBM" {
+		t.Errorf("expected only IBM to be sent, got %+v", msg)
+	}
+
+	for _, bad := range []string{`{"action": "watch", "symbols": ["AAPL"]}`, `{"action": "subscribe", "symbols": ["TOOLONG"]}`, `not json`} {
+		send(bad)
+		if msg := receive(); msg.Type != wsMessageError || msg.Symbol != "" || msg.Error == "" {
+			t.Errorf("expected error message for %s, got %+v", bad, msg)
+		}
+	}
+}
+
+func TestHandleWebSocketThroughMiddleware(t *testing.T) {
+	h := newTestHandler(&config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Hour}})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	server := httptest.NewServer(Gzip(Instrument(logger)(http.HandlerFunc(h.HandleWebSocket))))
+	defer server.Close()
+
+	header := http.Header{"Accept-Encoding": []string{"gzip"}}
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+	if err != nil {
+		t.Fatalf("dial through middleware failed: %v", err)
+	}
+	defer conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("expected status %d, got %d", http.StatusSwitchingProtocols, resp.StatusCode)
+	}
+}
+
+func TestWSSessionApply(t *testing.T) {
+	s := &wsSession{symbols: make(map[string]bool)}
+
+	added, err := s.apply([]byte(`{"action": "subscribe", "symbols": ["AAPL", "MSFT"]}`))
+	if err != nil || len(added) != 2 {
+		t.Fatalf("expected 2 symbols added, got %v, %v", added, err)
+	}
+	if _, err := s.apply([]byte(`{"action": "unsubscribe", "symbols": ["AAPL"]}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := s.subscribed(); len(got) != 1 || got[0] != "MSFT" {
+		t.Errorf("expected [MSFT] after unsubscribing, got %v", got)
+	}
+
+	many := `{"action": "subscribe", "symbols": ["A", "B", "C", "D", "E", "F", "G", "H", "I", "J"]}`
+	if _, err := s.apply([]byte(many)); err == nil {
+		t.Errorf("expected an error when exceeding %d subscriptions", maxSymbols)
+	}
+	if got := s.subscribed(); len(got) != 1 {
+		t.Errorf("expected a rejected subscribe to leave the set unchanged, got %v", got)
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{name: "no origin", want: true},
+		{name: "same origin", origin: "http://example.com", want: true},
+		{name: "cross origin", origin: "http://other.com", want: false},
+		{name: "allowed origin", allowed: []string{"http://other.com"}, origin: "http://other.com", want: true},
+		{name: "wildcard", allowed: []string{"*"}, origin: "http://other.com", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(&config.Config{ServerConfig: config.ServerConfig{AllowedOrigins: tt.allowed}})
+			r := httptest.NewRequest(http.MethodGet, "http://example.com/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if got := h.checkOrigin(r); got != tt.want {
+				t.Errorf("checkOrigin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
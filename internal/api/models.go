@@ -1,20 +1,224 @@
 package api
 
-import "github.com/saedabdu/stockticker/pkg/models"
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/saedabdu/stockticker/pkg/models"
+)
 
 // StockResponse represents the response sent to the client
 type StockResponse struct {
-	Symbol  string              `json:"symbol"`
-	Prices  []models.StockPrice `json:"prices"`
-	Average float64             `json:"average"`
+	XMLName xml.Name `json:"-" xml:"stock"`
+
+	Symbol  string              `json:"symbol" xml:"symbol"`
+	Prices  []models.StockPrice `json:"prices" xml:"prices>price"`
+	Average float64             `json:"average" xml:"average"`
+	Median  float64             `json:"median" xml:"median"`
+	Min     float64             `json:"min" xml:"min"`
+	Max     float64             `json:"max" xml:"max"`
+	StdDev  float64             `json:"std_dev" xml:"std_dev"`
+
+	PercentChange float64 `json:"percent_change" xml:"percent_change"`
+	Currency      string  `json:"currency" xml:"currency"`
+	LastRefreshed string  `json:"last_refreshed" xml:"last_refreshed"`
+	TimeZone      string  `json:"time_zone" xml:"time_zone"`
+	// RequestedDays is unset for date range queries; ReturnedDays is smaller when history is short
+	RequestedDays int `json:"requested_days,omitempty" xml:"requested_days,omitempty"`
+	ReturnedDays  int `json:"returned_days" xml:"returned_days"`
+	// PriceField is omitted when the statistics are computed from the closes
+	PriceField models.PriceField `json:"price_field,omitempty" xml:"price_field,omitempty"`
+
+	// CachedAt is when the data was fetched from the provider and cached
+	CachedAt time.Time `json:"cached_at" xml:"cached_at"`
+
+	SMA     []models.SeriesPoint `json:"sma,omitempty" xml:"sma>point,omitempty"`
+	EMA     []models.SeriesPoint `json:"ema,omitempty" xml:"ema>point,omitempty"`
+	Returns []models.SeriesPoint `json:"returns,omitempty" xml:"returns>point,omitempty"`
+
+	VWAP *float64 `json:"vwap,omitempty" xml:"vwap,omitempty"`
+
+	Page *models.Page `json:"page,omitempty" xml:"page,omitempty"`
+
+	Stale bool `json:"stale,omitempty" xml:"stale,omitempty"`
+}
+
+// LatestResponse represents the most recent close of a symbol
+type LatestResponse struct {
+	Symbol string  `json:"symbol"`
+	Date   string  `json:"date"`
+	Close  float64 `json:"close"`
+}
+
+// IntervalsResponse is the response to a multi-interval request, keyed by interval
+type IntervalsResponse struct {
+	Symbol    string                      `json:"symbol"`
+	Intervals map[string]IntervalResponse `json:"intervals"`
+}
+
+// IntervalResponse represents one interval of a multi-interval response.
+// On failure only the error is set.
+type IntervalResponse struct {
+	*StockResponse
+	Error string `json:"error,omitempty"`
+}
+
+// AlertResponse reports whether the latest daily change of a symbol reached the
+// alert threshold. ChangePercent is signed; the threshold applies to its magnitude.
+type AlertResponse struct {
+	Symbol        string  `json:"symbol"`
+	Date          string  `json:"date"`
+	ChangePercent float64 `json:"change_percent"`
+	Threshold     float64 `json:"threshold"`
+	Triggered     bool    `json:"triggered"`
+}
+
+// AverageResponse represents the average price of a symbol over the requested window
+type AverageResponse struct {
+	Symbol  string  `json:"symbol"`
+	Average float64 `json:"average"`
+	// PriceField is omitted when the average is of the closes
+	PriceField models.PriceField `json:"price_field,omitempty"`
+}
+
+// ExtremesResponse represents the highest and lowest close of a symbol over the
+// requested window
+type ExtremesResponse struct {
+	Symbol string     `json:"symbol"`
+	High   PricePoint `json:"high"`
+	Low    PricePoint `json:"low"`
+}
+
+// CorrelationResponse represents the Pearson correlation of the closes of two
+// symbols over the dates both have prices for
+type CorrelationResponse struct {
+	A           string  `json:"a"`
+	B           string  `json:"b"`
+	Points      int     `json:"points"`
+	Correlation float64 `json:"correlation"`
+}
+
+// PricePoint is a close on a given date
+type PricePoint struct {
+	Date  string  `json:"date"`
+	Close float64 `json:"close"`
+}
+
+// SymbolResponse represents one entry of a multi-symbol response.
+// On failure only the symbol and error are set.
+type SymbolResponse struct {
+	*StockResponse
+	Symbol string `json:"symbol"`
+	Error  string `json:"error,omitempty"`
+}
+
+// QuoteResponse represents one entry of a /quote response.
+// On failure only the symbol and error are set.
+type QuoteResponse struct {
+	Symbol string `json:"symbol"`
+	*QuoteData
+	Error string `json:"error,omitempty"`
+}
+
+// QuoteData is the latest price of a symbol and its change from the previous close
+type QuoteData struct {
+	Price            float64 `json:"price"`
+	Change           float64 `json:"change"`
+	ChangePercent    float64 `json:"change_percent"`
+	LatestTradingDay string  `json:"latest_trading_day"`
+}
+
+// PrefetchRequest is the body of a /prefetch request
+type PrefetchRequest struct {
+	Symbols []string `json:"symbols"`
+}
+
+// PrefetchResult reports whether one symbol of a /prefetch request was cached
+type PrefetchResult struct {
+	Symbol string `json:"symbol"`
+	Cached bool   `json:"cached"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Subscription is a message from a /ws client changing the symbols it receives
+type Subscription struct {
+	// Action is "subscribe" or "unsubscribe"
+	Action  string   `json:"action"`
+	Symbols []string `json:"symbols"`
+}
+
+// StreamMessage is a message pushed to a /ws client: the stock data for a
+// subscribed symbol, or an error for that symbol or for the last client message
+type StreamMessage struct {
+	Type   string         `json:"type"`
+	Symbol string         `json:"symbol,omitempty"`
+	Data   *StockResponse `json:"data,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// SearchResult represents one symbol matching a search
+type SearchResult struct {
+	Symbol   string `json:"symbol"`
+	Name     string `json:"name"`
+	Region   string `json:"region"`
+	Currency string `json:"currency"`
 }
 
 // ErrorResponse represents an error response sent to the client
 type ErrorResponse struct {
+	// Code is a machine-readable ErrorCode clients can branch on
+	Code ErrorCode `json:"code"`
+	// Error is a human-readable description of the failure
 	Error string `json:"error"`
 }
 
+// ErrorCode identifies the kind of failure in an ErrorResponse
+type ErrorCode string
+
+// Error codes of an ErrorResponse
+const (
+	ErrorCodeInvalidRequest   ErrorCode = "INVALID_REQUEST"
+	ErrorCodeUnauthorized     ErrorCode = "UNAUTHORIZED"
+	ErrorCodeSymbolNotAllowed ErrorCode = "SYMBOL_NOT_ALLOWED"
+	ErrorCodeInvalidSymbol    ErrorCode = "INVALID_SYMBOL"
+	ErrorCodeNotFound         ErrorCode = "NOT_FOUND"
+	ErrorCodeMethodNotAllowed ErrorCode = "METHOD_NOT_ALLOWED"
+	ErrorCodeRateLimited      ErrorCode = "RATE_LIMITED"
+	ErrorCodeNotImplemented   ErrorCode = "NOT_IMPLEMENTED"
+	ErrorCodeUpstreamError    ErrorCode = "UPSTREAM_ERROR"
+	ErrorCodeStaleData        ErrorCode = "STALE_DATA"
+	ErrorCodeUnavailable      ErrorCode = "UNAVAILABLE"
+	ErrorCodeInternal         ErrorCode = "INTERNAL_ERROR"
+)
+
 // HealthResponse represents a health check response
 type HealthResponse struct {
 	Status string `json:"status"`
+	// Upstream is "ok" or "unreachable" for a deep check and omitted otherwise
+	Upstream string `json:"upstream,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// VersionResponse identifies the running build. Commit and BuildTime are empty
+// when unknown.
+type VersionResponse struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
+// SymbolStatus represents the upstream fetch history of a symbol in a /status response
+type SymbolStatus struct {
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	ErrorCount  int        `json:"error_count"`
+}
+
+// CacheStatsResponse represents cache effectiveness statistics
+type CacheStatsResponse struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Items     int    `json:"items"`
 }
@@ -1,7 +1,10 @@
 package cache
 
 import (
+	"container/list"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -9,50 +12,114 @@ import (
 type Item struct {
 	Value      interface{}
 	Expiration int64
+	// CreatedAt is when the item was set, in nanoseconds since the epoch like Expiration
+	CreatedAt int64
 }
 
-// Cache is a simple in-memory cache with expiration
+// Age returns how long ago the item was set
+func (i Item) Age() time.Duration {
+	return time.Since(time.Unix(0, i.CreatedAt))
+}
+
+// Stats holds cache effectiveness counters
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Items     int
+}
+
+// entry is stored in the access-order list so eviction can find the key
+type entry struct {
+	key  string
+	item Item
+}
+
+// Cache is a simple in-memory cache with expiration and optional LRU eviction
 type Cache struct {
-	items map[string]Item
-	mu    sync.RWMutex
+	items    map[string]*list.Element
+	order    *list.List // front is most recently used
+	maxItems int
+	mu       sync.Mutex
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+
+	// janitor state, guarded by janitorMu
+	janitorMu sync.Mutex
+	stop      chan struct{}
+	wg        sync.WaitGroup
 }
 
-// New creates a new cache
-func New() *Cache {
+// New creates a new cache holding at most maxItems entries.
+// When maxItems is zero the cache is unbounded.
+func New(maxItems int) *Cache {
 	return &Cache{
-		items: make(map[string]Item),
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+		maxItems: maxItems,
 	}
 }
 
-// Set adds an item to the cache with the given key and expiration duration
+// Set adds an item to the cache with the given key and expiration duration.
+// If the cache is full, the least recently used item is evicted.
 func (c *Cache) Set(key string, value interface{}, duration time.Duration) {
+	now := time.Now()
+	c.setItem(key, Item{
+		Value:      value,
+		Expiration: now.Add(duration).UnixNano(),
+		CreatedAt:  now.UnixNano(),
+	})
+}
+
+// setItem stores item under key, evicting the least recently used item if the cache is full
+func (c *Cache) setItem(key string, item Item) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	expiration := time.Now().Add(duration).UnixNano()
-	c.items[key] = Item{
-		Value:      value,
-		Expiration: expiration,
+	if elem, found := c.items[key]; found {
+		elem.Value.(*entry).item = item
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&entry{key: key, item: item})
+
+	if c.maxItems > 0 && c.order.Len() > c.maxItems {
+		c.removeElement(c.order.Back())
+		c.evictions.Add(1)
 	}
 }
 
 // Get retrieves an item from the cache by key
 // The second return value indicates whether the key was found
 func (c *Cache) Get(key string) (interface{}, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	item, found := c.GetItem(key)
+	return item.Value, found
+}
+
+// GetItem retrieves an unexpired item along with its timestamps
+func (c *Cache) GetItem(key string) (Item, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	item, found := c.items[key]
+	elem, found := c.items[key]
 	if !found {
-		return nil, false
+		c.misses.Add(1)
+		return Item{}, false
 	}
 
 	// Check if the item has expired
+	item := elem.Value.(*entry).item
 	if time.Now().UnixNano() > item.Expiration {
-		return nil, false
+		c.misses.Add(1)
+		return Item{}, false
 	}
 
-	return item.Value, true
+	c.hits.Add(1)
+	c.order.MoveToFront(elem)
+	return item, true
 }
 
 // Delete removes an item from the cache
@@ -60,7 +127,21 @@ func (c *Cache) Delete(key string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	delete(c.items, key)
+	if elem, found := c.items[key]; found {
+		c.removeElement(elem)
+	}
+}
+
+// DeletePrefix removes every item whose key starts with prefix
+func (c *Cache) DeletePrefix(prefix string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, elem := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.removeElement(elem)
+		}
+	}
 }
 
 // Cleanup removes expired items from the cache
@@ -69,9 +150,75 @@ func (c *Cache) Cleanup() {
 	defer c.mu.Unlock()
 
 	now := time.Now().UnixNano()
-	for k, v := range c.items {
-		if now > v.Expiration {
-			delete(c.items, k)
+	for _, elem := range c.items {
+		if now > elem.Value.(*entry).item.Expiration {
+			c.removeElement(elem)
+		}
+	}
+}
+
+// Stats returns a snapshot of the cache hit, miss and eviction counters
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	items := len(c.items)
+	c.mu.Unlock()
+
+	return Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Evictions: c.evictions.Load(),
+		Items:     items,
+	}
+}
+
+// removeElement deletes elem from both the map and the access-order list.
+// The caller must hold c.mu.
+func (c *Cache) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*entry).key)
+}
+
+// StartJanitor launches a background goroutine that calls Cleanup every interval.
+// Calling it while a janitor is already running has no effect.
+func (c *Cache) StartJanitor(interval time.Duration) {
+	c.janitorMu.Lock()
+	defer c.janitorMu.Unlock()
+
+	if c.stop != nil {
+		return
+	}
+
+	c.stop = make(chan struct{})
+	c.wg.Add(1)
+	go c.runJanitor(interval, c.stop)
+}
+
+// Stop halts the janitor goroutine and waits for it to exit.
+// It is safe to call Stop multiple times or when no janitor is running.
+func (c *Cache) Stop() {
+	c.janitorMu.Lock()
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+	c.janitorMu.Unlock()
+
+	c.wg.Wait()
+}
+
+// runJanitor periodically removes expired items until stop is closed
+func (c *Cache) runJanitor(interval time.Duration, stop <-chan struct{}) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.Cleanup()
+		case <-stop:
+			return
 		}
 	}
 }
@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := New(2)
+
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Minute)
+
+	// Touch "a" so that "b" becomes the least recently used entry
+	if _, found := c.Get("a"); !found {
+		t.Fatal("expected a to be cached")
+	}
+
+	c.Set("c", 3, time.Minute)
+
+	if _, found := c.Get("b"); found {
+		t.Error("expected b to be evicted")
+	}
+	if _, found := c.Get("a"); !found {
+		t.Error("expected a to remain cached")
+	}
+	if _, found := c.Get("c"); !found {
+		t.Error("expected c to be cached")
+	}
+}
+
+func TestCacheUnboundedWhenMaxIsZero(t *testing.T) {
+	c := New(0)
+
+	for i := 0; i < 100; i++ {
+		c.Set(string(rune('a'+i)), i, time.Minute)
+	}
+
+	if got := len(c.items); got != 100 {
+		t.Errorf("expected 100 items, got %d", got)
+	}
+}
+
+func TestCacheStats(t *testing.T) {
+	c := New(1)
+
+	c.Set("a", 1, time.Minute)
+	c.Get("a")
+	c.Get("missing")
+	c.Set("b", 2, time.Minute)
+	c.Set("expired", 3, -time.Minute)
+	c.Get("expired")
+
+	stats := c.Stats()
+	if stats.Hits != 1 {
+		t.Errorf("expected 1 hit, got %d", stats.Hits)
+	}
+	if stats.Misses != 2 {
+		t.Errorf("expected 2 misses, got %d", stats.Misses)
+	}
+	if stats.Evictions != 2 {
+		t.Errorf("expected 2 evictions, got %d", stats.Evictions)
+	}
+}
+
+func TestCacheSaveAndLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.gob")
+
+	c := New(0)
+	c.Set("live", "value", time.Minute)
+	c.Set("expired", "value", -time.Minute)
+	if err := c.SaveFile(path); err != nil {
+		t.Fatalf("unexpected error saving: %v", err)
+	}
+
+	loaded := New(0)
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("unexpected error loading: %v", err)
+	}
+
+	original, _ := c.GetItem("live")
+	if item, found := loaded.GetItem("live"); !found || item.Value != "value" || item.CreatedAt != original.CreatedAt {
+		t.Errorf("expected live entry to be restored with its timestamps, got %+v, %v", item, found)
+	}
+	if len(loaded.items) != 1 {
+		t.Errorf("expected 1 restored item, got %d", len(loaded.items))
+	}
+
+	if err := New(0).LoadFile(filepath.Join(t.TempDir(), "missing.gob")); err != nil {
+		t.Errorf("expected no error for a missing file, got %v", err)
+	}
+}
+
+func TestCacheGetItemTracksCreatedAt(t *testing.T) {
+	c := New(0)
+	before := time.Now()
+	c.Set("key", "value", time.Minute)
+
+	item, found := c.GetItem("key")
+	if !found {
+		t.Fatal("expected item to be found")
+	}
+	createdAt := time.Unix(0, item.CreatedAt)
+	if createdAt.Before(before) || createdAt.After(time.Now()) {
+		t.Errorf("expected CreatedAt between %v and now, got %v", before, createdAt)
+	}
+	if age := item.Age(); age < 0 || age > time.Second {
+		t.Errorf("expected a small non-negative age, got %v", age)
+	}
+}
+
+func TestJitterStaysWithinPercent(t *testing.T) {
+	const duration = 10 * time.Minute
+	minSeen, maxSeen := duration, duration
+	for i := 0; i < 1000; i++ {
+		got := jitter(duration, 10)
+		if got < 9*time.Minute || got > 11*time.Minute {
+			t.Fatalf("jitter(%s, 10) = %s, outside ±10%%", duration, got)
+		}
+		minSeen, maxSeen = min(minSeen, got), max(maxSeen, got)
+	}
+	if minSeen == duration || maxSeen == duration {
+		t.Errorf("expected durations both above and below %s, got range %s-%s", duration, minSeen, maxSeen)
+	}
+}
+
+func TestWithJitterZeroReturnsStore(t *testing.T) {
+	store := New(0)
+	if got := WithJitter(store, 0); got != Store(store) {
+		t.Errorf("expected WithJitter with zero percent to return the store unchanged")
+	}
+
+	jittered := WithJitter(store, 10)
+	jittered.Set("key", "value", time.Minute)
+	if value, found := jittered.Get("key"); !found || value != "value" {
+		t.Errorf("expected value to be stored through the jittered store, got %v, %v", value, found)
+	}
+}
+
+func TestCacheDeletePrefix(t *testing.T) {
+	c := New(0)
+	c.Set("AAPL:7", 1, time.Minute)
+	c.Set("AAPL:30", 2, time.Minute)
+	c.Set("AAPLX:7", 3, time.Minute)
+
+	c.DeletePrefix("AAPL:")
+
+	if _, found := c.Get("AAPL:7"); found {
+		t.Errorf("expected AAPL:7 to be deleted")
+	}
+	if _, found := c.Get("AAPL:30"); found {
+		t.Errorf("expected AAPL:30 to be deleted")
+	}
+	if _, found := c.Get("AAPLX:7"); !found {
+		t.Errorf("expected AAPLX:7 outside the prefix to remain")
+	}
+	if got := c.Stats().Items; got != 1 {
+		t.Errorf("expected 1 item left, got %d", got)
+	}
+}
@@ -0,0 +1,34 @@
+package cache
+
+import (
+	"math/rand"
+	"time"
+)
+
+// jitterStore is a Store that randomly adjusts the duration of every Set, so
+// entries stored together do not all expire at the same moment
+type jitterStore struct {
+	Store
+	percent int
+}
+
+// WithJitter returns a Store that lengthens or shortens each Set duration by a
+// random amount of up to percent percent before passing it to store. A percent
+// of zero returns store unchanged.
+func WithJitter(store Store, percent int) Store {
+	if percent == 0 {
+		return store
+	}
+	return &jitterStore{Store: store, percent: percent}
+}
+
+// Set stores value under key for duration adjusted by the jitter
+func (s *jitterStore) Set(key string, value interface{}, duration time.Duration) {
+	s.Store.Set(key, value, jitter(duration, s.percent))
+}
+
+// jitter returns duration adjusted by a uniformly random amount within ±percent percent
+func jitter(duration time.Duration, percent int) time.Duration {
+	spread := float64(duration) * float64(percent) / 100
+	return duration + time.Duration((rand.Float64()*2-1)*spread)
+}
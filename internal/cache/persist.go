@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// persistedItem is the on-disk representation of a cache entry.
+// Concrete value types must be registered with gob.Register.
+type persistedItem struct {
+	Key        string
+	Value      interface{}
+	Expiration int64
+	CreatedAt  int64
+}
+
+// SaveFile writes all unexpired items to path using encoding/gob.
+// The file is written atomically by renaming a temporary file into place.
+func (c *Cache) SaveFile(path string) error {
+	c.mu.Lock()
+	now := time.Now().UnixNano()
+	items := make([]persistedItem, 0, len(c.items))
+	// Walk from least to most recently used so LoadFile restores the same order
+	for elem := c.order.Back(); elem != nil; elem = elem.Prev() {
+		e := elem.Value.(*entry)
+		if now > e.item.Expiration {
+			continue
+		}
+		items = append(items, persistedItem{Key: e.key, Value: e.item.Value, Expiration: e.item.Expiration, CreatedAt: e.item.CreatedAt})
+	}
+	c.mu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("error creating cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(items); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error encoding cache file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing cache file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error replacing cache file: %w", err)
+	}
+
+	return nil
+}
+
+// LoadFile adds the items saved by SaveFile at path to the cache, dropping any
+// that have expired since. A missing file is not an error.
+func (c *Cache) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error opening cache file: %w", err)
+	}
+	defer f.Close()
+
+	var items []persistedItem
+	if err := gob.NewDecoder(f).Decode(&items); err != nil {
+		return fmt.Errorf("error decoding cache file: %w", err)
+	}
+
+	now := time.Now().UnixNano()
+	for _, item := range items {
+		if now > item.Expiration {
+			continue
+		}
+		c.setItem(item.Key, Item{Value: item.Value, Expiration: item.Expiration, CreatedAt: item.CreatedAt})
+	}
+
+	return nil
+}
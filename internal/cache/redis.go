@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisKeyPrefix namespaces the keys written to a shared Redis database
+	redisKeyPrefix = "stockticker:"
+	// redisTimeout bounds each call to Redis so a slow server degrades to a cache miss
+	redisTimeout = 500 * time.Millisecond
+)
+
+// Redis is a cache backed by a Redis server, shared by every replica that uses it.
+// Items are gob-encoded, so the concrete types of their values must be registered
+// with gob.Register.
+type Redis struct {
+	client *redis.Client
+	logger *slog.Logger
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewRedis creates a cache connected to the Redis server at the given URL,
+// e.g. redis://:password@localhost:6379/0
+func NewRedis(url string, logger *slog.Logger) (*Redis, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis URL: %w", err)
+	}
+
+	return &Redis{
+		client: redis.NewClient(opts),
+		logger: logger,
+	}, nil
+}
+
+// Ping checks that the Redis server is reachable
+func (r *Redis) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+// Close closes the connection to the Redis server
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+// Get retrieves an item from the cache. Redis errors are logged and reported as a miss.
+func (r *Redis) Get(key string) (interface{}, bool) {
+	item, found := r.GetItem(key)
+	return item.Value, found
+}
+
+// GetItem retrieves an item along with its timestamps. Redis errors are logged
+// and reported as a miss.
+func (r *Redis) GetItem(key string) (Item, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	data, err := r.client.Get(ctx, redisKeyPrefix+key).Bytes()
+	if err != nil {
+		if !errors.Is(err, redis.Nil) {
+			r.logger.Warn("error reading from Redis cache", "key", key, "error", err)
+		}
+		r.misses.Add(1)
+		return Item{}, false
+	}
+
+	var item Item
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&item); err != nil {
+		r.logger.Warn("error decoding Redis cache value", "key", key, "error", err)
+		r.misses.Add(1)
+		return Item{}, false
+	}
+
+	r.hits.Add(1)
+	return item, true
+}
+
+// Set adds an item to the cache, expiring it after duration. Redis errors are logged.
+func (r *Redis) Set(key string, value interface{}, duration time.Duration) {
+	now := time.Now()
+	item := Item{Value: value, Expiration: now.Add(duration).UnixNano(), CreatedAt: now.UnixNano()}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&item); err != nil {
+		r.logger.Warn("error encoding Redis cache value", "key", key, "error", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	if err := r.client.Set(ctx, redisKeyPrefix+key, buf.Bytes(), duration).Err(); err != nil {
+		r.logger.Warn("error writing to Redis cache", "key", key, "error", err)
+	}
+}
+
+// Delete removes an item from the cache
+func (r *Redis) Delete(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	if err := r.client.Del(ctx, redisKeyPrefix+key).Err(); err != nil {
+		r.logger.Warn("error deleting from Redis cache", "key", key, "error", err)
+	}
+}
+
+// DeletePrefix removes every item whose key starts with prefix. Prefixes are
+// matched as Redis glob patterns, so they must not contain *, ?, [ or \.
+func (r *Redis) DeletePrefix(prefix string) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	iter := r.client.Scan(ctx, 0, redisKeyPrefix+prefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		if err := r.client.Del(ctx, iter.Val()).Err(); err != nil {
+			r.logger.Warn("error deleting from Redis cache", "key", strings.TrimPrefix(iter.Val(), redisKeyPrefix), "error", err)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		r.logger.Warn("error scanning Redis cache keys", "prefix", prefix, "error", err)
+	}
+}
+
+// Cleanup is a no-op since Redis expires keys itself
+func (r *Redis) Cleanup() {}
+
+// Stats returns the hit and miss counters of this process along with the
+// number of keys in Redis. Evictions are managed by Redis and not reported.
+func (r *Redis) Stats() Stats {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	items := 0
+	iter := r.client.Scan(ctx, 0, redisKeyPrefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		items++
+	}
+	if err := iter.Err(); err != nil {
+		r.logger.Warn("error counting Redis cache keys", "error", err)
+	}
+
+	return Stats{
+		Hits:   r.hits.Load(),
+		Misses: r.misses.Load(),
+		Items:  items,
+	}
+}
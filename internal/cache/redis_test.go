@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"encoding/gob"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+type redisTestValue struct {
+	Name string
+}
+
+func TestRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	gob.Register(&redisTestValue{})
+
+	c, err := NewRedis("redis://"+server.Addr(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+
+	if _, found := c.Get("missing"); found {
+		t.Errorf("expected miss for unknown key")
+	}
+
+	c.Set("key", &redisTestValue{Name: "value"}, time.Minute)
+	value, found := c.Get("key")
+	if !found {
+		t.Fatalf("expected hit after Set")
+	}
+	if got, ok := value.(*redisTestValue); !ok || got.Name != "value" {
+		t.Errorf("expected &{value}, got %#v", value)
+	}
+
+	if ttl := server.TTL(redisKeyPrefix + "key"); ttl != time.Minute {
+		t.Errorf("expected TTL of 1m, got %v", ttl)
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Items != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	if item, found := c.GetItem("key"); !found || item.Age() < 0 || item.Age() > time.Second {
+		t.Errorf("expected the item to report when it was set, got %+v, %v", item, found)
+	}
+
+	server.FastForward(time.Minute)
+	if _, found := c.Get("key"); found {
+		t.Errorf("expected miss after expiry")
+	}
+
+	c.Set("key", &redisTestValue{Name: "value"}, time.Minute)
+	c.Delete("key")
+	if _, found := c.Get("key"); found {
+		t.Errorf("expected miss after Delete")
+	}
+
+	c.Set("AAPL:7", &redisTestValue{Name: "a"}, time.Minute)
+	c.Set("AAPL:30", &redisTestValue{Name: "b"}, time.Minute)
+	c.Set("AAPLX:7", &redisTestValue{Name: "c"}, time.Minute)
+	c.DeletePrefix("AAPL:")
+	if _, found := c.Get("AAPL:7"); found {
+		t.Errorf("expected miss after DeletePrefix")
+	}
+	if _, found := c.Get("AAPL:30"); found {
+		t.Errorf("expected miss after DeletePrefix")
+	}
+	if _, found := c.Get("AAPLX:7"); !found {
+		t.Errorf("expected keys outside the prefix to remain")
+	}
+}
@@ -0,0 +1,26 @@
+package cache
+
+import "time"
+
+// Store is a cache backend. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the unexpired value stored under key
+	Get(key string) (interface{}, bool)
+	// GetItem returns the unexpired item stored under key along with when it was set
+	GetItem(key string) (Item, bool)
+	// Set stores value under key for the given duration
+	Set(key string, value interface{}, duration time.Duration)
+	// Delete removes key from the cache
+	Delete(key string)
+	// DeletePrefix removes every key starting with prefix
+	DeletePrefix(prefix string)
+	// Cleanup removes expired items
+	Cleanup()
+	// Stats returns the cache effectiveness counters
+	Stats() Stats
+}
+
+var (
+	_ Store = (*Cache)(nil)
+	_ Store = (*Redis)(nil)
+)
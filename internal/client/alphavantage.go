@@ -1,19 +1,26 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"time"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 
 	"github.com/saedabdu/stockticker/pkg/models"
 )
 
 const (
-	baseURL  = "https://www.alphavantage.co/query"
-	function = "TIME_SERIES_DAILY"
+	baseURL = "https://www.alphavantage.co/query"
+	// providerAlphaVantage labels metrics for this provider
+	providerAlphaVantage = "alphavantage"
 	// Alpha Vantage outputsize options
 	outputSizeCompact = "compact" // Returns the latest 100 data points
 	outputSizeFull    = "full"    // Returns up to 20+ years of historical data
@@ -21,24 +28,94 @@ const (
 	compactOutputSizeLimit = 100
 )
 
+// tracer creates the clients' spans; it is a no-op unless tracing is configured
+var tracer = otel.Tracer("github.com/saedabdu/stockticker/internal/client")
+
+// Interval selects the granularity of the time series
+type Interval string
+
+// Supported time series intervals
+const (
+	IntervalDaily   Interval = "daily"
+	IntervalWeekly  Interval = "weekly"
+	IntervalMonthly Interval = "monthly"
+
+	Interval1Min  Interval = "1min"
+	Interval5Min  Interval = "5min"
+	Interval15Min Interval = "15min"
+	Interval30Min Interval = "30min"
+	Interval60Min Interval = "60min"
+)
+
+// Alpha Vantage API functions without an interval
+const (
+	intradayFunction      = "TIME_SERIES_INTRADAY"
+	dailyAdjustedFunction = "TIME_SERIES_DAILY_ADJUSTED"
+	exchangeRateFunction  = "CURRENCY_EXCHANGE_RATE"
+	symbolSearchFunction  = "SYMBOL_SEARCH"
+	globalQuoteFunction   = "GLOBAL_QUOTE"
+)
+
+// functions maps each interval to its Alpha Vantage API function
+var functions = map[Interval]string{
+	IntervalDaily:   "TIME_SERIES_DAILY",
+	IntervalWeekly:  "TIME_SERIES_WEEKLY",
+	IntervalMonthly: "TIME_SERIES_MONTHLY",
+	Interval1Min:    intradayFunction,
+	Interval5Min:    intradayFunction,
+	Interval15Min:   intradayFunction,
+	Interval30Min:   intradayFunction,
+	Interval60Min:   intradayFunction,
+}
+
+// ParseInterval converts a string into a supported Interval
+func ParseInterval(s string) (Interval, error) {
+	interval := Interval(s)
+	if _, ok := functions[interval]; !ok {
+		return "", fmt.Errorf("invalid interval %q: must be one of daily, weekly, monthly, 1min, 5min, 15min, 30min, 60min", s)
+	}
+	return interval, nil
+}
+
+// IsIntraday reports whether the interval is an intraday interval, whose
+// time series is keyed by timestamp rather than date
+func (i Interval) IsIntraday() bool {
+	return functions[i] == intradayFunction
+}
+
 // AlphaVantage is the AlphaVantage API client
 type AlphaVantage struct {
-	apiKey     string
-	httpClient *http.Client
+	apiKey string
+	options
 }
 
 // NewAlphaVantage creates a new AlphaVantage API client
-func NewAlphaVantage(apiKey string) *AlphaVantage {
-	return &AlphaVantage{
-		apiKey: apiKey,
-		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+func NewAlphaVantage(apiKey string, opts ...Option) *AlphaVantage {
+	return &AlphaVantage{apiKey: apiKey, options: newOptions(baseURL, opts)}
+}
+
+// GetStockData retrieves stock data at the given interval from the AlphaVantage API
+func (c *AlphaVantage) GetStockData(ctx context.Context, symbol string, days int, interval Interval) (*models.AlphaVantageResponse, error) {
+	if interval.IsIntraday() {
+		return c.GetIntradayData(ctx, symbol, interval)
+	}
+
+	function, ok := functions[interval]
+	if !ok {
+		return nil, fmt.Errorf("unsupported interval %q", interval)
 	}
+
+	return c.query(ctx, c.timeSeriesParams(function, symbol, days))
+}
+
+// GetAdjustedStockData retrieves the daily time series with closes adjusted for
+// splits and dividends from the AlphaVantage API
+func (c *AlphaVantage) GetAdjustedStockData(ctx context.Context, symbol string, days int) (*models.AlphaVantageResponse, error) {
+	return c.query(ctx, c.timeSeriesParams(dailyAdjustedFunction, symbol, days))
 }
 
-// GetStockData retrieves stock data from the AlphaVantage API
-func (c *AlphaVantage) GetStockData(symbol string, days int) (*models.AlphaVantageResponse, error) {
+// timeSeriesParams returns the parameters requesting at least days entries of a time series function
+func (c *AlphaVantage) timeSeriesParams(function, symbol string, days int) url.Values {
 	params := url.Values{}
 	params.Add("apikey", c.apiKey)
 	params.Add("function", function)
@@ -51,28 +128,259 @@ func (c *AlphaVantage) GetStockData(symbol string, days int) (*models.AlphaVanta
 		params.Add("outputsize", outputSizeCompact)
 	}
 
-	reqURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
+	return params
+}
+
+// GetIntradayData retrieves the latest intraday stock data at the given interval from the AlphaVantage API
+func (c *AlphaVantage) GetIntradayData(ctx context.Context, symbol string, interval Interval) (*models.AlphaVantageResponse, error) {
+	if !interval.IsIntraday() {
+		return nil, fmt.Errorf("unsupported intraday interval %q: must be one of 1min, 5min, 15min, 30min, 60min", interval)
+	}
+
+	params := url.Values{}
+	params.Add("apikey", c.apiKey)
+	params.Add("function", intradayFunction)
+	params.Add("symbol", symbol)
+	params.Add("interval", string(interval))
+	params.Add("outputsize", outputSizeCompact)
+
+	return c.query(ctx, params)
+}
 
-	resp, err := c.httpClient.Get(reqURL)
+// exchangeRateResponse is the response of the CURRENCY_EXCHANGE_RATE function
+type exchangeRateResponse struct {
+	ExchangeRate struct {
+		Rate string `json:"5. Exchange Rate"`
+	} `json:"Realtime Currency Exchange Rate"`
+	Note        string `json:"Note,omitempty"`
+	Information string `json:"Information,omitempty"`
+}
+
+// GetExchangeRate retrieves the realtime exchange rate between two currencies from the AlphaVantage API
+func (c *AlphaVantage) GetExchangeRate(ctx context.Context, from, to string) (float64, error) {
+	params := url.Values{}
+	params.Add("apikey", c.apiKey)
+	params.Add("function", exchangeRateFunction)
+	params.Add("from_currency", from)
+	params.Add("to_currency", to)
+
+	var rate float64
+	err := c.call(ctx, params, func(body io.Reader) error {
+		var result exchangeRateResponse
+		if err := json.NewDecoder(body).Decode(&result); err != nil {
+			return fmt.Errorf("error decoding Alpha Vantage response: %w", err)
+		}
+
+		if result.ExchangeRate.Rate == "" {
+			if isRateLimitNotice(result.Note, result.Information) {
+				return fmt.Errorf("%w: Alpha Vantage: %s", ErrRateLimited, strings.TrimSpace(result.Note+" "+result.Information))
+			}
+			return fmt.Errorf("no exchange rate returned from Alpha Vantage for %s to %s, possibly invalid currency", from, to)
+		}
+
+		var err error
+		if rate, err = strconv.ParseFloat(result.ExchangeRate.Rate, 64); err != nil {
+			return fmt.Errorf("error parsing exchange rate for %s to %s: %w", from, to, err)
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("error making request to Alpha Vantage: %w", err)
+		return 0, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Alpha Vantage API error (status code %d): %s", resp.StatusCode, string(bodyBytes))
+	return rate, nil
+}
+
+// symbolSearchResponse is the response of the SYMBOL_SEARCH function
+type symbolSearchResponse struct {
+	BestMatches []struct {
+		Symbol   string `json:"1. symbol"`
+		Name     string `json:"2. name"`
+		Region   string `json:"4. region"`
+		Currency string `json:"8. currency"`
+	} `json:"bestMatches"`
+	Note        string `json:"Note,omitempty"`
+	Information string `json:"Information,omitempty"`
+}
+
+// SearchSymbols retrieves the symbols best matching keywords from the AlphaVantage API
+func (c *AlphaVantage) SearchSymbols(ctx context.Context, keywords string) ([]models.SymbolMatch, error) {
+	params := url.Values{}
+	params.Add("apikey", c.apiKey)
+	params.Add("function", symbolSearchFunction)
+	params.Add("keywords", keywords)
+
+	var result symbolSearchResponse
+	err := c.call(ctx, params, func(body io.Reader) error {
+		result = symbolSearchResponse{}
+		if err := json.NewDecoder(body).Decode(&result); err != nil {
+			return fmt.Errorf("error decoding Alpha Vantage response: %w", err)
+		}
+
+		// An empty bestMatches list is a valid result, so only a missing one is checked
+		if result.BestMatches == nil && isRateLimitNotice(result.Note, result.Information) {
+			return fmt.Errorf("%w: Alpha Vantage: %s", ErrRateLimited, strings.TrimSpace(result.Note+" "+result.Information))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	var result models.AlphaVantageResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("error decoding Alpha Vantage response: %w", err)
+	matches := make([]models.SymbolMatch, 0, len(result.BestMatches))
+	for _, match := range result.BestMatches {
+		matches = append(matches, models.SymbolMatch{
+			Symbol:   match.Symbol,
+			Name:     match.Name,
+			Region:   match.Region,
+			Currency: match.Currency,
+		})
+	}
+
+	return matches, nil
+}
+
+// globalQuoteResponse is the response of the GLOBAL_QUOTE function. The quote
+// is empty for an unknown symbol.
+type globalQuoteResponse struct {
+	GlobalQuote struct {
+		Symbol           string `json:"01. symbol"`
+		Price            string `json:"05. price"`
+		LatestTradingDay string `json:"07. latest trading day"`
+		Change           string `json:"09. change"`
+		ChangePercent    string `json:"10. change percent"`
+	} `json:"Global Quote"`
+	Note         string `json:"Note,omitempty"`
+	Information  string `json:"Information,omitempty"`
+	ErrorMessage string `json:"Error Message,omitempty"`
+}
+
+// GetGlobalQuote retrieves the latest quote of symbol from the AlphaVantage API
+func (c *AlphaVantage) GetGlobalQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	params := url.Values{}
+	params.Add("apikey", c.apiKey)
+	params.Add("function", globalQuoteFunction)
+	params.Add("symbol", symbol)
+
+	var result globalQuoteResponse
+	err := c.call(ctx, params, func(body io.Reader) error {
+		result = globalQuoteResponse{}
+		if err := json.NewDecoder(body).Decode(&result); err != nil {
+			return fmt.Errorf("error decoding Alpha Vantage response: %w", err)
+		}
+
+		if result.GlobalQuote.Price == "" {
+			if isRateLimitNotice(result.Note, result.Information) {
+				return fmt.Errorf("%w: Alpha Vantage: %s", ErrRateLimited, strings.TrimSpace(result.Note+" "+result.Information))
+			}
+			if result.ErrorMessage != "" {
+				return &InvalidSymbolError{Symbol: symbol, Message: result.ErrorMessage}
+			}
+			return fmt.Errorf("%w: no quote returned from Alpha Vantage for %s", ErrInvalidSymbol, symbol)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Check for error messages in the response
-	if result.TimeSeries == nil || len(result.TimeSeries) == 0 {
-		return nil, fmt.Errorf("no data returned from Alpha Vantage, possibly invalid symbol or API key")
+	quote := &models.Quote{Symbol: result.GlobalQuote.Symbol, LatestTradingDay: result.GlobalQuote.LatestTradingDay}
+	if quote.Price, err = strconv.ParseFloat(result.GlobalQuote.Price, 64); err != nil {
+		return nil, fmt.Errorf("error parsing price for %s: %w", symbol, err)
+	}
+	if quote.Change, err = strconv.ParseFloat(result.GlobalQuote.Change, 64); err != nil {
+		return nil, fmt.Errorf("error parsing change for %s: %w", symbol, err)
+	}
+	// The percentage is formatted with a trailing percent sign, e.g. "1.2345%"
+	if quote.ChangePercent, err = strconv.ParseFloat(strings.TrimSuffix(result.GlobalQuote.ChangePercent, "%"), 64); err != nil {
+		return nil, fmt.Errorf("error parsing change percent for %s: %w", symbol, err)
+	}
+
+	return quote, nil
+}
+
+// query calls the AlphaVantage API for a time series with the given parameters
+func (c *AlphaVantage) query(ctx context.Context, params url.Values) (*models.AlphaVantageResponse, error) {
+	var result models.AlphaVantageResponse
+	err := c.call(ctx, params, func(body io.Reader) error {
+		result = models.AlphaVantageResponse{}
+		if err := json.NewDecoder(body).Decode(&result); err != nil {
+			return fmt.Errorf("error decoding Alpha Vantage response: %w", err)
+		}
+
+		// Check for error messages in the response
+		if len(result.TimeSeries) == 0 && isRateLimitNotice(result.Note, result.Information) {
+			return fmt.Errorf("%w: Alpha Vantage: %s", ErrRateLimited, strings.TrimSpace(result.Note+" "+result.Information))
+		}
+		if len(result.TimeSeries) == 0 && result.ErrorMessage != "" {
+			return &InvalidSymbolError{Symbol: params.Get("symbol"), Message: result.ErrorMessage}
+		}
+		if len(result.TimeSeries) == 0 {
+			return fmt.Errorf("%w: no data returned from Alpha Vantage, possibly invalid symbol or API key", ErrInvalidSymbol)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &result, nil
 }
+
+// call requests the AlphaVantage API with the given parameters and passes the
+// response body to decode, retrying transient failures
+func (c *AlphaVantage) call(ctx context.Context, params url.Values, decode func(io.Reader) error) (err error) {
+	ctx, span := tracer.Start(ctx, "AlphaVantage "+params.Get("function"))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+	if symbol := params.Get("symbol"); symbol != "" {
+		span.SetAttributes(attribute.String("symbol", symbol))
+	}
+
+	reqURL := fmt.Sprintf("%s?%s", c.baseURL, params.Encode())
+
+	return c.retry(ctx, providerAlphaVantage, func() error {
+		return c.fetch(ctx, reqURL, decode)
+	})
+}
+
+// fetch performs a single request to the AlphaVantage API
+func (c *AlphaVantage) fetch(ctx context.Context, reqURL string, decode func(io.Reader) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("error creating Alpha Vantage request: %w", err)
+	}
+	setRequestHeaders(req, c.userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return &retryableError{err: fmt.Errorf("%w: error making request to Alpha Vantage: %w", ErrUpstreamUnavailable, err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(limitBody(resp.Body, c.maxResponseSize))
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return &retryableError{err: fmt.Errorf("%w: Alpha Vantage API error (status code %d): %s", ErrUpstreamUnavailable, resp.StatusCode, string(bodyBytes))}
+		}
+		return fmt.Errorf("Alpha Vantage API error (status code %d): %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	return decode(limitBody(resp.Body, c.maxResponseSize))
+}
+
+// isRateLimitNotice reports whether the Note or Information field of a response
+// without data is an Alpha Vantage rate limit notice
+func isRateLimitNotice(note, information string) bool {
+	if note != "" {
+		return true
+	}
+
+	info := strings.ToLower(information)
+	return strings.Contains(info, "rate limit") || strings.Contains(info, "call frequency")
+}
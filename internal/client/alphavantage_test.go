@@ -0,0 +1,417 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/requestid"
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+// roundTripFunc allows a function to be used as an http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestClient returns a client whose requests are answered with the given status and body
+func newTestClient(statusCode int, body string) *AlphaVantage {
+	httpClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: statusCode,
+				Body:       io.NopCloser(strings.NewReader(body)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+	return NewAlphaVantage("test-key", WithRetry(0, 0), WithHTTPClient(httpClient))
+}
+
+func TestGetStockDataRateLimited(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{
+			name: "note field",
+			body: `{"Note": "Thank you for using Alpha Vantage! Our standard API call frequency is 5 calls per minute and 500 calls per day."}`,
+		},
+		{
+			name: "information field",
+			body: `{"Information": "Thank you for using Alpha Vantage! Our standard API rate limit is 25 requests per day."}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(http.StatusOK, tt.body)
+
+			_, err := c.GetStockData(context.Background(), "IBM", 7, IntervalDaily)
+			if !errors.Is(err, ErrRateLimited) {
+				t.Fatalf("expected ErrRateLimited, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "Alpha Vantage") {
+				t.Errorf("expected the error to name Alpha Vantage, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGetStockDataNoData(t *testing.T) {
+	c := newTestClient(http.StatusOK, `{}`)
+
+	_, err := c.GetStockData(context.Background(), "IBM", 7, IntervalDaily)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected a non rate limit error, got %v", err)
+	}
+	if !errors.Is(err, ErrInvalidSymbol) {
+		t.Errorf("expected ErrInvalidSymbol, got %v", err)
+	}
+}
+
+func TestGetStockDataErrorMessage(t *testing.T) {
+	message := "Invalid API call. Please retry or visit the documentation (https://www.alphavantage.co/documentation/) for TIME_SERIES_DAILY."
+	c := newTestClient(http.StatusOK, `{"Error Message": "`+message+`"}`)
+
+	_, err := c.GetStockData(context.Background(), "NOPE", 7, IntervalDaily)
+	if !errors.Is(err, ErrInvalidSymbol) {
+		t.Fatalf("expected ErrInvalidSymbol, got %v", err)
+	}
+
+	var invalid *InvalidSymbolError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected an InvalidSymbolError, got %T", err)
+	}
+	if invalid.Symbol != "NOPE" || invalid.Message != message {
+		t.Errorf("expected symbol NOPE with the upstream message, got %+v", invalid)
+	}
+}
+
+func TestGetStockDataServerError(t *testing.T) {
+	c := newTestClient(http.StatusServiceUnavailable, `upstream down`)
+
+	_, err := c.GetStockData(context.Background(), "IBM", 7, IntervalDaily)
+	if !errors.Is(err, ErrUpstreamUnavailable) {
+		t.Errorf("expected ErrUpstreamUnavailable, got %v", err)
+	}
+}
+
+func TestGetStockDataResponseTooLarge(t *testing.T) {
+	body := `{"Time Series (Daily)": {"2023-01-06": {"4. close": "143.70"}}}`
+
+	c := newTestClient(http.StatusOK, body)
+	WithMaxResponseSize(int64(len(body) - 1))(&c.options)
+	if _, err := c.GetStockData(context.Background(), "IBM", 7, IntervalDaily); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("expected ErrResponseTooLarge, got %v", err)
+	}
+
+	c = newTestClient(http.StatusOK, body)
+	WithMaxResponseSize(int64(len(body)))(&c.options)
+	if _, err := c.GetStockData(context.Background(), "IBM", 7, IntervalDaily); err != nil {
+		t.Errorf("expected a body at the limit to decode, got %v", err)
+	}
+}
+
+func TestGetStockDataWeekly(t *testing.T) {
+	body := `{
+		"Meta Data": {"2. Symbol": "IBM", "3. Last Refreshed": "2023-01-06", "4. Time Zone": "US/Eastern"},
+		"Weekly Time Series": {
+			"2023-01-06": {"1. open": "141.10", "2. high": "144.25", "3. low": "140.01", "4. close": "143.70", "5. volume": "13648000"}
+		}
+	}`
+
+	var gotFunction string
+	c := newTestClient(http.StatusOK, body)
+	transport := c.httpClient.Transport
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		gotFunction = req.URL.Query().Get("function")
+		return transport.RoundTrip(req)
+	})
+
+	result, err := c.GetStockData(context.Background(), "IBM", 7, IntervalWeekly)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotFunction != "TIME_SERIES_WEEKLY" {
+		t.Errorf("expected function TIME_SERIES_WEEKLY, got %s", gotFunction)
+	}
+	if got := result.TimeSeries["2023-01-06"].Close; got != "143.70" {
+		t.Errorf("expected close 143.70, got %s", got)
+	}
+	if result.MetaData.LastRefreshed != "2023-01-06" || result.MetaData.TimeZone != "US/Eastern" {
+		t.Errorf("expected metadata 2023-01-06 US/Eastern, got %+v", result.MetaData)
+	}
+}
+
+func TestGetIntradayData(t *testing.T) {
+	body := `{
+		"Meta Data": {"2. Symbol": "IBM", "3. Last Refreshed": "2023-01-06 16:00:00", "4. Interval": "5min", "6. Time Zone": "US/Eastern"},
+		"Time Series (5min)": {
+			"2023-01-06 16:00:00": {"1. open": "143.50", "2. high": "143.80", "3. low": "143.45", "4. close": "143.70", "5. volume": "250000"}
+		}
+	}`
+	c := newTestClient(http.StatusOK, body)
+
+	result, err := c.GetIntradayData(context.Background(), "IBM", Interval5Min)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := result.TimeSeries["2023-01-06 16:00:00"].Close; got != "143.70" {
+		t.Errorf("expected close 143.70, got %s", got)
+	}
+	if result.MetaData.TimeZone != "US/Eastern" {
+		t.Errorf("expected time zone US/Eastern, got %s", result.MetaData.TimeZone)
+	}
+
+	if _, err := c.GetIntradayData(context.Background(), "IBM", IntervalDaily); err == nil {
+		t.Error("expected error for non-intraday interval, got nil")
+	}
+}
+
+func TestGetStockDataWithTestServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("symbol"); got != "IBM" {
+			t.Errorf("expected symbol IBM, got %s", got)
+		}
+		w.Write([]byte(`{"Time Series (Daily)": {"2023-01-06": {"4. close": "143.70"}}}`))
+	}))
+	defer server.Close()
+
+	c := NewAlphaVantage("test-key", WithRetry(0, 0), WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+
+	result, err := c.GetStockData(context.Background(), "IBM", 7, IntervalDaily)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := result.TimeSeries["2023-01-06"].Close; got != "143.70" {
+		t.Errorf("expected close 143.70, got %s", got)
+	}
+}
+
+func TestGetStockDataSendsHeaders(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []Option
+		wantAgent string
+	}{
+		{name: "default user agent", wantAgent: defaultUserAgent},
+		{name: "configured user agent", opts: []Option{WithUserAgent("stockticker/1.2.3")}, wantAgent: "stockticker/1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("User-Agent"); got != tt.wantAgent {
+					t.Errorf("expected User-Agent %q, got %q", tt.wantAgent, got)
+				}
+				if got := r.Header.Get(requestid.Header); got != "req-1" {
+					t.Errorf("expected %s req-1, got %q", requestid.Header, got)
+				}
+				w.Write([]byte(`{"Time Series (Daily)": {"2023-01-06": {"4. close": "143.70"}}}`))
+			}))
+			defer server.Close()
+
+			opts := append([]Option{WithRetry(0, 0), WithBaseURL(server.URL), WithHTTPClient(server.Client())}, tt.opts...)
+			c := NewAlphaVantage("test-key", opts...)
+
+			ctx := requestid.NewContext(context.Background(), "req-1")
+			if _, err := c.GetStockData(ctx, "IBM", 7, IntervalDaily); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetStockDataRateLimitWaitExceedsDeadline(t *testing.T) {
+	body := `{"Time Series (Daily)": {"2023-01-06": {"4. close": "143.70"}}}`
+	c := newTestClient(http.StatusOK, body)
+	WithRateLimit(1)(&c.options)
+
+	if _, err := c.GetStockData(context.Background(), "IBM", 7, IntervalDaily); err != nil {
+		t.Fatalf("unexpected error on first call: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := c.GetStockData(ctx, "IBM", 7, IntervalDaily); err == nil {
+		t.Fatal("expected rate limiter error, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("expected prompt failure, took %s", elapsed)
+	}
+}
+
+func TestGetExchangeRate(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		want        float64
+		wantErr     bool
+		rateLimited bool
+	}{
+		{
+			name: "rate returned",
+			body: `{"Realtime Currency Exchange Rate": {"1. From_Currency Code": "USD", "3. To_Currency Code": "EUR", "5. Exchange Rate": "0.92150000"}}`,
+			want: 0.9215,
+		},
+		{
+			name:        "rate limited",
+			body:        `{"Note": "Thank you for using Alpha Vantage! Our standard API call frequency is 5 calls per minute and 500 calls per day."}`,
+			wantErr:     true,
+			rateLimited: true,
+		},
+		{
+			name:    "invalid currency",
+			body:    `{"Error Message": "Invalid API call."}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(http.StatusOK, tt.body)
+
+			rate, err := c.GetExchangeRate(context.Background(), "USD", "EUR")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if errors.Is(err, ErrRateLimited) != tt.rateLimited {
+				t.Errorf("expected rate limited %v, got %v", tt.rateLimited, err)
+			}
+			if rate != tt.want {
+				t.Errorf("expected rate %v, got %v", tt.want, rate)
+			}
+		})
+	}
+}
+
+func TestGetGlobalQuote(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		want          *models.Quote
+		rateLimited   bool
+		invalidSymbol bool
+	}{
+		{
+			name: "quote returned",
+			body: `{"Global Quote": {"01. symbol": "IBM", "05. price": "185.5000", "07. latest trading day": "2024-03-01", "08. previous close": "183.0000", "09. change": "2.5000", "10. change percent": "1.3661%"}}`,
+			want: &models.Quote{Symbol: "IBM", Price: 185.5, Change: 2.5, ChangePercent: 1.3661, LatestTradingDay: "2024-03-01"},
+		},
+		{
+			name:        "rate limited",
+			body:        `{"Note": "Thank you for using Alpha Vantage! Our standard API call frequency is 5 calls per minute and 500 calls per day."}`,
+			rateLimited: true,
+		},
+		{
+			name:          "unknown symbol",
+			body:          `{"Global Quote": {}}`,
+			invalidSymbol: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(http.StatusOK, tt.body)
+
+			quote, err := c.GetGlobalQuote(context.Background(), "IBM")
+			if errors.Is(err, ErrRateLimited) != tt.rateLimited {
+				t.Errorf("expected rate limited %v, got %v", tt.rateLimited, err)
+			}
+			if errors.Is(err, ErrInvalidSymbol) != tt.invalidSymbol {
+				t.Errorf("expected invalid symbol %v, got %v", tt.invalidSymbol, err)
+			}
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("expected an error, got quote %+v", quote)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if *quote != *tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, quote)
+			}
+		})
+	}
+}
+
+func TestSearchSymbols(t *testing.T) {
+	body := `{
+		"bestMatches": [
+			{"1. symbol": "AAPL", "2. name": "Apple Inc", "3. type": "Equity", "4. region": "United States", "8. currency": "USD", "9. matchScore": "0.8889"},
+			{"1. symbol": "APLE", "2. name": "Apple Hospitality REIT Inc", "3. type": "Equity", "4. region": "United States", "8. currency": "USD", "9. matchScore": "0.7143"}
+		]
+	}`
+	c := newTestClient(http.StatusOK, body)
+
+	matches, err := c.SearchSymbols(context.Background(), "apple")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(matches))
+	}
+	want := models.SymbolMatch{Symbol: "AAPL", Name: "Apple Inc", Region: "United States", Currency: "USD"}
+	if matches[0] != want {
+		t.Errorf("expected %+v, got %+v", want, matches[0])
+	}
+}
+
+func TestSearchSymbolsNoMatches(t *testing.T) {
+	c := newTestClient(http.StatusOK, `{"bestMatches": []}`)
+
+	matches, err := c.SearchSymbols(context.Background(), "zzzzzz")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("expected no matches, got %d", len(matches))
+	}
+}
+
+func TestGetAdjustedStockData(t *testing.T) {
+	body := `{
+		"Meta Data": {"2. Symbol": "IBM", "3. Last Refreshed": "2023-01-06", "5. Time Zone": "US/Eastern"},
+		"Time Series (Daily)": {
+			"2023-01-06": {"1. open": "141.10", "2. high": "144.25", "3. low": "140.01", "4. close": "143.70", "5. adjusted close": "138.52", "6. volume": "3574042", "7. dividend amount": "0.0000", "8. split coefficient": "1.0"}
+		}
+	}`
+
+	var gotFunction string
+	c := newTestClient(http.StatusOK, body)
+	transport := c.httpClient.Transport
+	c.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		gotFunction = req.URL.Query().Get("function")
+		return transport.RoundTrip(req)
+	})
+
+	result, err := c.GetAdjustedStockData(context.Background(), "IBM", 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if gotFunction != "TIME_SERIES_DAILY_ADJUSTED" {
+		t.Errorf("expected function TIME_SERIES_DAILY_ADJUSTED, got %s", gotFunction)
+	}
+	want := models.DailyPrice{Open: "141.10", High: "144.25", Low: "140.01", Close: "143.70", Volume: "3574042", AdjustedClose: "138.52"}
+	if got := result.TimeSeries["2023-01-06"]; got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
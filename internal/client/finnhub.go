@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+const (
+	finnhubBaseURL = "https://finnhub.io/api/v1"
+	// finnhubCandlePath is the candle endpoint, relative to the base URL
+	finnhubCandlePath = "/stock/candle"
+	// providerFinnhub labels metrics for this provider
+	providerFinnhub = "finnhub"
+	// Finnhub reports candle times as UNIX timestamps in UTC
+	finnhubTimeZone = "UTC"
+	// Extra lookback so weekends and holidays don't shorten the window
+	finnhubLookbackPadding = 7 * 24 * time.Hour
+)
+
+// finnhubResolutions maps each interval to its Finnhub candle resolution
+var finnhubResolutions = map[Interval]string{
+	IntervalDaily:   "D",
+	IntervalWeekly:  "W",
+	IntervalMonthly: "M",
+	Interval1Min:    "1",
+	Interval5Min:    "5",
+	Interval15Min:   "15",
+	Interval30Min:   "30",
+	Interval60Min:   "60",
+}
+
+// finnhubPeriods is the length of one candle for each interval
+var finnhubPeriods = map[Interval]time.Duration{
+	IntervalDaily:   24 * time.Hour,
+	IntervalWeekly:  7 * 24 * time.Hour,
+	IntervalMonthly: 31 * 24 * time.Hour,
+	Interval1Min:    time.Minute,
+	Interval5Min:    5 * time.Minute,
+	Interval15Min:   15 * time.Minute,
+	Interval30Min:   30 * time.Minute,
+	Interval60Min:   time.Hour,
+}
+
+// Finnhub is the Finnhub API client
+type Finnhub struct {
+	apiKey string
+	options
+}
+
+// Ensure Finnhub satisfies the StockProvider interface
+var _ StockProvider = (*Finnhub)(nil)
+
+// finnhubCandles represents the response from the Finnhub candle endpoint.
+// Each slice holds one value per candle, aligned by index.
+type finnhubCandles struct {
+	Open      []float64 `json:"o"`
+	High      []float64 `json:"h"`
+	Low       []float64 `json:"l"`
+	Close     []float64 `json:"c"`
+	Volume    []float64 `json:"v"`
+	Timestamp []int64   `json:"t"`
+	Status    string    `json:"s"`
+}
+
+// NewFinnhub creates a new Finnhub API client. It accepts the same options as
+// NewAlphaVantage, with WithBaseURL replacing the https://finnhub.io/api/v1 prefix.
+func NewFinnhub(apiKey string, opts ...Option) *Finnhub {
+	return &Finnhub{apiKey: apiKey, options: newOptions(finnhubBaseURL, opts)}
+}
+
+// GetStockData retrieves stock candles from the Finnhub API and maps them into
+// the shared response model, retrying transient failures
+func (c *Finnhub) GetStockData(ctx context.Context, symbol string, days int, interval Interval) (*models.AlphaVantageResponse, error) {
+	resolution, ok := finnhubResolutions[interval]
+	if !ok {
+		return nil, fmt.Errorf("unsupported interval %q", interval)
+	}
+
+	to := time.Now()
+	from := to.Add(-(time.Duration(days)*finnhubPeriods[interval]*2 + finnhubLookbackPadding))
+
+	params := url.Values{}
+	params.Add("token", c.apiKey)
+	params.Add("symbol", symbol)
+	params.Add("resolution", resolution)
+	params.Add("from", strconv.FormatInt(from.Unix(), 10))
+	params.Add("to", strconv.FormatInt(to.Unix(), 10))
+
+	reqURL := fmt.Sprintf("%s%s?%s", c.baseURL, finnhubCandlePath, params.Encode())
+
+	var candles *finnhubCandles
+	err := c.retry(ctx, providerFinnhub, func() (err error) {
+		candles, err = c.fetch(ctx, reqURL)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return candles.toAlphaVantageResponse(symbol, interval)
+}
+
+// fetch performs a single request to the Finnhub candle endpoint
+func (c *Finnhub) fetch(ctx context.Context, reqURL string) (*finnhubCandles, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Finnhub request: %w", err)
+	}
+	setRequestHeaders(req, c.userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, &retryableError{err: fmt.Errorf("%w: error making request to Finnhub: %w", ErrUpstreamUnavailable, err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, fmt.Errorf("%w: Finnhub returned status code %d", ErrRateLimited, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(limitBody(resp.Body, c.maxResponseSize))
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return nil, &retryableError{err: fmt.Errorf("%w: Finnhub API error (status code %d): %s", ErrUpstreamUnavailable, resp.StatusCode, string(bodyBytes))}
+		}
+		return nil, fmt.Errorf("Finnhub API error (status code %d): %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var candles finnhubCandles
+	if err := json.NewDecoder(limitBody(resp.Body, c.maxResponseSize)).Decode(&candles); err != nil {
+		return nil, fmt.Errorf("error decoding Finnhub response: %w", err)
+	}
+
+	if candles.Status != "ok" || len(candles.Timestamp) == 0 {
+		return nil, fmt.Errorf("%w: no data returned from Finnhub, possibly invalid symbol or API key", ErrInvalidSymbol)
+	}
+
+	return &candles, nil
+}
+
+// toAlphaVantageResponse maps the column-oriented candles into a date-keyed time series
+func (f *finnhubCandles) toAlphaVantageResponse(symbol string, interval Interval) (*models.AlphaVantageResponse, error) {
+	n := len(f.Timestamp)
+	if len(f.Open) != n || len(f.High) != n || len(f.Low) != n || len(f.Close) != n || len(f.Volume) != n {
+		return nil, fmt.Errorf("malformed Finnhub response: candle arrays have mismatched lengths")
+	}
+
+	layout := "2006-01-02"
+	if interval.IsIntraday() {
+		layout = "2006-01-02 15:04:05"
+	}
+
+	timeSeries := make(map[string]models.DailyPrice, n)
+	for i, ts := range f.Timestamp {
+		key := time.Unix(ts, 0).UTC().Format(layout)
+		timeSeries[key] = models.DailyPrice{
+			Open:   formatPrice(f.Open[i]),
+			High:   formatPrice(f.High[i]),
+			Low:    formatPrice(f.Low[i]),
+			Close:  formatPrice(f.Close[i]),
+			Volume: strconv.FormatInt(int64(f.Volume[i]), 10),
+		}
+	}
+
+	lastRefreshed := time.Unix(f.Timestamp[n-1], 0).UTC().Format(layout)
+
+	return &models.AlphaVantageResponse{
+		MetaData: models.MetaData{
+			Information:   fmt.Sprintf("Finnhub %s candles", interval),
+			Symbol:        symbol,
+			LastRefreshed: lastRefreshed,
+			TimeZone:      finnhubTimeZone,
+		},
+		TimeSeries: timeSeries,
+	}, nil
+}
+
+// formatPrice renders a price in the string form used by the shared response model
+func formatPrice(price float64) string {
+	return strconv.FormatFloat(price, 'f', -1, 64)
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestFinnhubCandlesToAlphaVantageResponse(t *testing.T) {
+	candles := &finnhubCandles{
+		Open:      []float64{141.1, 143.5},
+		High:      []float64{144.25, 145},
+		Low:       []float64{140.01, 142.2},
+		Close:     []float64{143.7, 144.9},
+		Volume:    []float64{13648000, 9500000},
+		Timestamp: []int64{1672963200, 1673222400}, // 2023-01-06, 2023-01-09 00:00 UTC
+		Status:    "ok",
+	}
+
+	result, err := candles.toAlphaVantageResponse("IBM", IntervalDaily)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	price, ok := result.TimeSeries["2023-01-09"]
+	if !ok {
+		t.Fatalf("expected entry for 2023-01-09, got %v", result.TimeSeries)
+	}
+	if price.Open != "143.5" || price.High != "145" || price.Low != "142.2" || price.Close != "144.9" || price.Volume != "9500000" {
+		t.Errorf("unexpected price entry: %+v", price)
+	}
+	if result.MetaData.LastRefreshed != "2023-01-09" {
+		t.Errorf("expected LastRefreshed 2023-01-09, got %s", result.MetaData.LastRefreshed)
+	}
+	if len(result.TimeSeries) != 2 {
+		t.Errorf("expected 2 entries, got %d", len(result.TimeSeries))
+	}
+}
+
+func TestFinnhubCandlesMismatchedLengths(t *testing.T) {
+	candles := &finnhubCandles{
+		Open:      []float64{141.1},
+		High:      []float64{144.25},
+		Low:       []float64{140.01},
+		Close:     []float64{143.7, 144.9},
+		Volume:    []float64{13648000},
+		Timestamp: []int64{1672963200},
+		Status:    "ok",
+	}
+
+	if _, err := candles.toAlphaVantageResponse("IBM", IntervalDaily); err == nil {
+		t.Error("expected error for mismatched candle arrays, got nil")
+	}
+}
+
+func TestFinnhubGetStockData(t *testing.T) {
+	const candles = `{"o":[100],"h":[105],"l":[99],"c":[102],"v":[1000],"t":[1672704000],"s":"ok"}`
+
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantErr   error
+		wantCalls int32
+	}{
+		{name: "success", statuses: []int{http.StatusOK}, wantCalls: 1},
+		{name: "retried server error", statuses: []int{http.StatusBadGateway, http.StatusOK}, wantCalls: 2},
+		{name: "rate limited", statuses: []int{http.StatusTooManyRequests}, wantErr: ErrRateLimited, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := calls.Add(1)
+				if r.URL.Path != finnhubCandlePath || r.Header.Get("User-Agent") != "test-agent" {
+					t.Errorf("unexpected request %s with User-Agent %q", r.URL.Path, r.Header.Get("User-Agent"))
+				}
+				status := tt.statuses[min(int(n), len(tt.statuses))-1]
+				w.WriteHeader(status)
+				if status == http.StatusOK {
+					_, _ = w.Write([]byte(candles))
+				}
+			}))
+			defer server.Close()
+
+			c := NewFinnhub("test-key", WithRetry(1, 0), WithBaseURL(server.URL), WithHTTPClient(server.Client()), WithUserAgent("test-agent"))
+			data, err := c.GetStockData(context.Background(), "IBM", 7, IntervalDaily)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !strings.Contains(err.Error(), "Finnhub") {
+					t.Errorf("expected %v naming Finnhub, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if got := data.TimeSeries["2023-01-03"].Close; got != "102" {
+				t.Errorf("expected close 102, got %q", got)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+// mockDailyFixture is 100 trading days of daily prices in the Alpha Vantage format
+//
+//go:embed fixtures/daily.json
+var mockDailyFixture []byte
+
+// mockFixtureSymbol is the symbol the fixture prices belong to; it is served unscaled
+const mockFixtureSymbol = "IBM"
+
+// Mock is an offline provider serving canned daily prices, for local development
+// and demos without an API key or network access
+type Mock struct{}
+
+// Ensure Mock satisfies the StockProvider interface
+var _ StockProvider = (*Mock)(nil)
+
+// NewMock creates a new Mock provider
+func NewMock() *Mock {
+	return &Mock{}
+}
+
+// GetStockData returns the embedded fixture for symbol. Prices are scaled by a
+// factor derived from the symbol so different symbols get different, but stable,
+// prices. Only the daily interval is available.
+func (m *Mock) GetStockData(ctx context.Context, symbol string, days int, interval Interval) (*models.AlphaVantageResponse, error) {
+	if interval != IntervalDaily {
+		return nil, fmt.Errorf("%w: the mock provider only serves daily data, not %q", ErrIntervalUnsupported, interval)
+	}
+
+	var result models.AlphaVantageResponse
+	if err := json.Unmarshal(mockDailyFixture, &result); err != nil {
+		return nil, fmt.Errorf("error decoding mock fixture: %w", err)
+	}
+
+	factor := mockPriceFactor(symbol)
+	for date, price := range result.TimeSeries {
+		scaled, err := scalePrice(price, factor)
+		if err != nil {
+			return nil, fmt.Errorf("error scaling mock price for date %s: %w", date, err)
+		}
+		result.TimeSeries[date] = scaled
+	}
+	result.MetaData.Symbol = symbol
+
+	return &result, nil
+}
+
+// mockPriceFactor returns 1 for the fixture's own symbol and a stable factor
+// between 0.5 and 2 for any other
+func mockPriceFactor(symbol string) float64 {
+	if symbol == mockFixtureSymbol {
+		return 1
+	}
+	h := fnv.New32a()
+	h.Write([]byte(symbol))
+	return 0.5 + float64(h.Sum32()%1500)/1000
+}
+
+// scalePrice multiplies the open, high, low and close of price by factor
+func scalePrice(price models.DailyPrice, factor float64) (models.DailyPrice, error) {
+	for _, field := range []*string{&price.Open, &price.High, &price.Low, &price.Close} {
+		value, err := strconv.ParseFloat(*field, 64)
+		if err != nil {
+			return models.DailyPrice{}, err
+		}
+		*field = strconv.FormatFloat(value*factor, 'f', 4, 64)
+	}
+	return price, nil
+}
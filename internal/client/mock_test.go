@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestMockGetStockData(t *testing.T) {
+	m := NewMock()
+
+	fixture, err := m.GetStockData(context.Background(), "IBM", 7, IntervalDaily)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fixture.TimeSeries) != 100 {
+		t.Errorf("expected 100 entries, got %d", len(fixture.TimeSeries))
+	}
+	if fixture.MetaData.LastRefreshed == "" {
+		t.Error("expected LastRefreshed to be set")
+	}
+
+	other, err := m.GetStockData(context.Background(), "AAPL", 7, IntervalDaily)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if other.MetaData.Symbol != "AAPL" {
+		t.Errorf("expected symbol AAPL, got %s", other.MetaData.Symbol)
+	}
+
+	// Every price of another symbol is scaled by the same factor
+	date := fixture.MetaData.LastRefreshed
+	base, _ := strconv.ParseFloat(fixture.TimeSeries[date].Close, 64)
+	scaled, _ := strconv.ParseFloat(other.TimeSeries[date].Close, 64)
+	if factor := mockPriceFactor("AAPL"); scaled < base*factor-0.001 || scaled > base*factor+0.001 {
+		t.Errorf("expected close %.4f scaled by %.3f, got %.4f", base, factor, scaled)
+	}
+
+	if _, err := m.GetStockData(context.Background(), "IBM", 7, IntervalWeekly); !errors.Is(err, ErrIntervalUnsupported) {
+		t.Errorf("expected ErrIntervalUnsupported for a non-daily interval, got %v", err)
+	}
+}
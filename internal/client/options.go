@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"golang.org/x/time/rate"
+
+	"github.com/saedabdu/stockticker/internal/metrics"
+)
+
+const (
+	// defaultTimeout bounds each request to the API
+	defaultTimeout = 10 * time.Second
+	// Default retry behaviour for transient upstream failures
+	defaultMaxRetries     = 3
+	defaultRetryBaseDelay = 500 * time.Millisecond
+)
+
+// options holds the HTTP settings shared by the provider clients
+type options struct {
+	baseURL        string
+	httpClient     *http.Client
+	timeout        time.Duration
+	maxRetries     int
+	retryBaseDelay time.Duration
+	limiter        *rate.Limiter
+	userAgent      string
+	// maxResponseSize bounds the bytes decoded from each response body
+	maxResponseSize int64
+}
+
+// Option configures an AlphaVantage or Finnhub client
+type Option func(*options)
+
+// newOptions returns the settings for a client of the API at baseURL with opts applied
+func newOptions(baseURL string, opts []Option) options {
+	o := options{
+		baseURL:         baseURL,
+		timeout:         defaultTimeout,
+		maxRetries:      defaultMaxRetries,
+		retryBaseDelay:  defaultRetryBaseDelay,
+		userAgent:       defaultUserAgent,
+		maxResponseSize: DefaultMaxResponseSize,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.httpClient == nil {
+		o.httpClient = &http.Client{
+			Timeout: o.timeout,
+			// Propagate trace context to the API and record a span per attempt
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+		}
+	}
+
+	return o
+}
+
+// WithRetry sets how many times a transient failure is retried and the base delay
+// used for exponential backoff between attempts. A maxRetries of zero disables retries.
+func WithRetry(maxRetries int, baseDelay time.Duration) Option {
+	return func(o *options) {
+		o.maxRetries = maxRetries
+		o.retryBaseDelay = baseDelay
+	}
+}
+
+// WithTimeout sets the timeout applied to each request to the API.
+// It has no effect when a client is supplied with WithHTTPClient.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithHTTPClient sets the http.Client used for requests, for example to point
+// the client at a test server or to wrap its transport with instrumentation
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = httpClient
+	}
+}
+
+// WithRateLimit limits requests to the API to requestsPerMinute, allowing bursts
+// of up to requestsPerMinute requests. A value of zero disables rate limiting.
+func WithRateLimit(requestsPerMinute int) Option {
+	return func(o *options) {
+		if requestsPerMinute <= 0 {
+			o.limiter = nil
+			return
+		}
+		o.limiter = rate.NewLimiter(rate.Limit(float64(requestsPerMinute)/60), requestsPerMinute)
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with each request
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithMaxResponseSize limits the bytes read from each response body, failing
+// with ErrResponseTooLarge beyond it. A value of zero removes the limit.
+func WithMaxResponseSize(bytes int64) Option {
+	return func(o *options) {
+		o.maxResponseSize = bytes
+	}
+}
+
+// WithBaseURL overrides the API endpoint, for example with an httptest.Server URL
+func WithBaseURL(url string) Option {
+	return func(o *options) {
+		o.baseURL = url
+	}
+}
+
+// backoff returns the delay before the given retry attempt using exponential backoff with jitter
+func (o *options) backoff(attempt int) time.Duration {
+	delay := o.retryBaseDelay << (attempt - 1)
+	if delay <= 0 {
+		return 0
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	return delay + jitter
+}
+
+// retry calls attempt until it succeeds, fails with an error that is not a
+// retryableError, or maxRetries retries have failed, backing off between
+// attempts. Each attempt waits for the rate limiter and is recorded in the
+// upstream metrics under provider.
+func (o *options) retry(ctx context.Context, provider string, attempt func() error) error {
+	var lastErr error
+	for n := 0; n <= o.maxRetries; n++ {
+		if n > 0 {
+			if err := sleep(ctx, o.backoff(n)); err != nil {
+				return fmt.Errorf("error making request to %s: %w", provider, err)
+			}
+		}
+
+		// Wait returns immediately if the wait would exceed the context deadline
+		if o.limiter != nil {
+			if err := o.limiter.Wait(ctx); err != nil {
+				return fmt.Errorf("error waiting for %s rate limiter: %w", provider, err)
+			}
+		}
+
+		start := time.Now()
+		err := attempt()
+		metrics.UpstreamDuration.WithLabelValues(provider, metrics.Outcome(err)).Observe(time.Since(start).Seconds())
+		if err == nil {
+			return nil
+		}
+
+		lastErr = err
+		var retryErr *retryableError
+		if !errors.As(err, &retryErr) || ctx.Err() != nil {
+			return err
+		}
+	}
+
+	return lastErr
+}
+
+// sleep waits for the given duration or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryableError marks an error as transient so the request may be retried
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/saedabdu/stockticker/internal/requestid"
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+// defaultUserAgent identifies this service in requests to the providers
+const defaultUserAgent = "stockticker"
+
+// DefaultMaxResponseSize bounds the bytes read from a provider response body
+const DefaultMaxResponseSize = 10 << 20
+
+// Errors shared by the providers. Each provider wraps them with its own name and details.
+var (
+	// ErrRateLimited is returned when the provider rejects a call because the rate limit was exceeded
+	ErrRateLimited = errors.New("upstream rate limit exceeded")
+	// ErrInvalidSymbol is returned when the provider has no data for the requested symbol
+	ErrInvalidSymbol = errors.New("symbol not found")
+	// ErrUpstreamUnavailable is returned when the provider cannot be reached or fails with a server error
+	ErrUpstreamUnavailable = errors.New("upstream provider unavailable")
+	// ErrResponseTooLarge is returned when a provider response body exceeds the size limit
+	ErrResponseTooLarge = errors.New("upstream response too large")
+	// ErrIntervalUnsupported is returned when the provider cannot serve the requested interval
+	ErrIntervalUnsupported = errors.New("interval not supported by the configured provider")
+)
+
+// InvalidSymbolError is returned when the provider rejects a symbol with its own
+// explanation. It matches ErrInvalidSymbol with errors.Is.
+type InvalidSymbolError struct {
+	Symbol string
+	// Message is the provider's explanation, e.g. Alpha Vantage's "Error Message"
+	Message string
+}
+
+func (e *InvalidSymbolError) Error() string {
+	return fmt.Sprintf("symbol %s not found: %s", e.Symbol, e.Message)
+}
+
+// Is reports whether target is ErrInvalidSymbol
+func (e *InvalidSymbolError) Is(target error) bool {
+	return target == ErrInvalidSymbol
+}
+
+// limitBody returns a reader of body that fails with ErrResponseTooLarge once
+// more than limit bytes are read, rather than truncating silently. A limit of
+// zero or less leaves body unbounded.
+func limitBody(body io.Reader, limit int64) io.Reader {
+	if limit <= 0 {
+		return body
+	}
+	return &limitedReader{r: io.LimitReader(body, limit+1), remaining: limit, limit: limit}
+}
+
+// limitedReader reads at most limit bytes from r, which must be limited to one more byte
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), fmt.Errorf("%w: exceeds %d bytes", ErrResponseTooLarge, l.limit)
+	}
+	return n, err
+}
+
+// setRequestHeaders identifies the service with userAgent, when set, and passes
+// on the request ID from the request's context so upstream logs can be correlated
+func setRequestHeaders(req *http.Request, userAgent string) {
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	if id := requestid.FromContext(req.Context()); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+}
+
+// StockProvider is a source of stock time series data
+type StockProvider interface {
+	// GetStockData retrieves at least days entries of the time series for symbol at the given interval
+	GetStockData(ctx context.Context, symbol string, days int, interval Interval) (*models.AlphaVantageResponse, error)
+}
+
+// AdjustedStockProvider is implemented by providers that can return closes adjusted for splits and dividends
+type AdjustedStockProvider interface {
+	// GetAdjustedStockData retrieves at least days entries of the daily time series
+	// for symbol, with AdjustedClose set on every entry
+	GetAdjustedStockData(ctx context.Context, symbol string, days int) (*models.AlphaVantageResponse, error)
+}
+
+// ExchangeRateProvider is implemented by providers that can convert between currencies
+type ExchangeRateProvider interface {
+	// GetExchangeRate returns how many units of the to currency one unit of the from currency buys
+	GetExchangeRate(ctx context.Context, from, to string) (float64, error)
+}
+
+// SymbolSearcher is implemented by providers that can look up symbols by company name
+type SymbolSearcher interface {
+	// SearchSymbols returns the best matches for keywords, ordered by relevance
+	SearchSymbols(ctx context.Context, keywords string) ([]models.SymbolMatch, error)
+}
+
+// QuoteProvider is implemented by providers that can return the latest quote of a symbol
+type QuoteProvider interface {
+	// GetGlobalQuote returns the latest price of symbol and its change from the previous close
+	GetGlobalQuote(ctx context.Context, symbol string) (*models.Quote, error)
+}
+
+// Ensure AlphaVantage satisfies the provider interfaces
+var (
+	_ StockProvider         = (*AlphaVantage)(nil)
+	_ AdjustedStockProvider = (*AlphaVantage)(nil)
+	_ ExchangeRateProvider  = (*AlphaVantage)(nil)
+	_ SymbolSearcher        = (*AlphaVantage)(nil)
+	_ QuoteProvider         = (*AlphaVantage)(nil)
+)
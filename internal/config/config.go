@@ -2,8 +2,16 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"net/netip"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/client"
+	"github.com/saedabdu/stockticker/internal/version"
 )
 
 // Default values
@@ -11,40 +19,619 @@ const (
 	DefaultPort   = "8080"
 	DefaultSymbol = "IBM"
 	DefaultNDays  = 7
+
+	DefaultMaxRetries      = 3
+	DefaultRetryBaseDelay  = 500 * time.Millisecond
+	DefaultUpstreamTimeout = 10 * time.Second
+
+	// Default HTTP server timeouts
+	DefaultServerReadTimeout  = 5 * time.Second
+	DefaultServerWriteTimeout = 10 * time.Second
+	DefaultServerIdleTimeout  = 120 * time.Second
+	// DefaultUserAgent identifies this service to the providers, followed by the version
+	DefaultUserAgent = "stockticker"
+	// DefaultRequestsPerMinute matches the Alpha Vantage free tier
+	DefaultRequestsPerMinute = 5
+	// DefaultClientRequestsPerMinute is the per-client limit on data endpoints
+	DefaultClientRequestsPerMinute = 60
+	// DefaultConcurrency bounds the upstream fetches of a multi-symbol request
+	DefaultConcurrency = 4
+
+	DefaultCacheMaxItems = 1000
+	DefaultCacheTTL      = 15 * time.Minute
+	DefaultCacheBackend  = CacheBackendMemory
+	DefaultRedisURL      = "redis://localhost:6379/0"
+
+	DefaultProvider = ProviderAlphaVantage
+
+	DefaultLogLevel = "info"
+)
+
+// MaxNDays caps NDAYS at roughly 20 years of trading days
+const MaxNDays = 20 * 252
+
+// MaxRoundAverage is the largest number of decimals ROUND_AVERAGE accepts
+const MaxRoundAverage = 10
+
+// Supported stock data providers
+const (
+	ProviderAlphaVantage = "alphavantage"
+	ProviderFinnhub      = "finnhub"
+	// ProviderMock serves canned data without an API key or network access
+	ProviderMock = "mock"
+)
+
+// Supported cache backends
+const (
+	CacheBackendMemory = "memory"
+	CacheBackendRedis  = "redis"
 )
 
-// Config holds the application configuration
+// Supported OpenTelemetry span exporters
+const (
+	// TracingExporterOTLP sends spans over OTLP/HTTP, configured by the standard
+	// OTEL_EXPORTER_OTLP_* environment variables
+	TracingExporterOTLP = "otlp"
+	// TracingExporterStdout writes spans to standard output, for local debugging
+	TracingExporterStdout = "stdout"
+)
+
+// symbolPattern matches 1-5 uppercase letters with an optional share class suffix such as BRK.B
+var symbolPattern = regexp.MustCompile(`^[A-Z]{1,5}(\.[A-Z])?$`)
+
+// Config holds the application configuration. Settings are grouped into
+// embedded sub-structs whose fields are promoted, so cfg.CacheTTL and
+// cfg.CacheConfig.CacheTTL are the same field.
 type Config struct {
-	Port   string
-	APIKey string
+	ServerConfig
+	CacheConfig
+	ClientConfig
+	ProviderConfig
+
 	Symbol string
 	NDays  int
+	// Symbols are the watched symbols from CONFIG_FILE with their own settings
+	Symbols []SymbolConfig
+	// AllowedSymbols restricts the symbols that may be requested; empty allows all
+	AllowedSymbols []string
+
+	// RoundAverage rounds the average close to this many decimals before it is
+	// cached and returned; zero keeps full precision
+	RoundAverage int
+
+	LogLevel slog.Level
+	// TracingExporter selects where OpenTelemetry spans are sent; empty disables tracing
+	TracingExporter string
 }
 
-// New creates a new Config with values from environment variables or defaults
+// ServerConfig holds the settings of the HTTP server and its middleware
+type ServerConfig struct {
+	Port string
+	// BindAddress is the host or IP the server listens on; empty listens on all interfaces
+	BindAddress string
+	// Server timeouts for reading a request, writing a response and keeping an
+	// idle connection open; zero disables the timeout
+	ServerReadTimeout  time.Duration
+	ServerWriteTimeout time.Duration
+	ServerIdleTimeout  time.Duration
+	// ClientRequestsPerMinute limits requests per client IP; zero disables the limit
+	ClientRequestsPerMinute int
+	// TrustedProxies are the peers whose X-Forwarded-For header is used to find
+	// the client IP; requests from any other peer are keyed on their own address
+	TrustedProxies []netip.Prefix
+
+	AllowedOrigins []string
+	// AuthToken is the bearer token required on data endpoints; empty disables authentication
+	AuthToken string
+	// TLSCertFile and TLSKeyFile serve HTTPS, with HTTP/2, when set; both or neither are set
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+// CacheConfig holds the settings of the cache and of keeping it warm
+type CacheConfig struct {
+	CacheMaxItems int
+	CacheTTL      time.Duration
+	// CacheTTLJitter randomly lengthens or shortens each cache TTL by up to this
+	// percentage so entries stored together expire at different times
+	CacheTTLJitter int
+	// CacheFile persists the cache across restarts when set
+	CacheFile string
+	// CacheBackend selects the in-process cache or a Redis cache shared by replicas
+	CacheBackend string
+	RedisURL     string
+	// Prefetch warms the cache with the configured symbols at startup
+	Prefetch bool
+	// StaleIfError is how long past CacheTTL cached data is kept and served when
+	// fetching fresh data fails; zero disables serving stale data
+	StaleIfError time.Duration
+	// RefreshAheadWindow is how long before expiry recently requested entries are
+	// re-fetched in the background; zero disables refresh-ahead
+	RefreshAheadWindow time.Duration
+}
+
+// ClientConfig holds the settings of requests to the upstream provider
+type ClientConfig struct {
+	MaxRetries      int
+	RetryBaseDelay  time.Duration
+	UpstreamTimeout time.Duration
+	// UserAgent is sent in the User-Agent header of requests to the provider
+	UserAgent string
+	// MaxResponseSize is the largest provider response body in bytes that is decoded
+	MaxResponseSize int64
+	// RequestsPerMinute limits calls to the upstream provider; zero disables the limit
+	RequestsPerMinute int
+	// Concurrency is the number of symbols of a multi-symbol request fetched at once
+	Concurrency int
+	// SymbolLock allows only one upstream fetch per symbol at a time across all
+	// windows, intervals and code paths
+	SymbolLock bool
+}
+
+// ProviderConfig selects the upstream provider and how far its data is trusted
+type ProviderConfig struct {
+	Provider string
+	APIKey   string
+	// MaxStaleness is how long ago the provider may have last refreshed the data
+	// before it is marked stale, or rejected when RejectStale is set; zero disables the check
+	MaxStaleness time.Duration
+	RejectStale  bool
+}
+
+// New creates a new Config with values from environment variables, the optional
+// CONFIG_FILE, or defaults, in that order of precedence
 func New() (*Config, error) {
-	port := getEnvOrDefault("PORT", DefaultPort)
-	apiKey := os.Getenv("API_KEY")
-	symbol := getEnvOrDefault("SYMBOL", DefaultSymbol)
+	file := &fileConfig{}
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		var err error
+		if file, err = loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	serverConfig, err := newServerConfig(file)
+	if err != nil {
+		return nil, err
+	}
+	cacheConfig, err := newCacheConfig(file)
+	if err != nil {
+		return nil, err
+	}
+	clientConfig, err := newClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	providerConfig, err := newProviderConfig(file)
+	if err != nil {
+		return nil, err
+	}
 
-	nDaysStr := getEnvOrDefault("NDAYS", strconv.Itoa(DefaultNDays))
+	symbol := getEnvOrDefault("SYMBOL", firstNonEmpty(file.Symbol, DefaultSymbol))
+	if err := ValidateSymbol(symbol); err != nil {
+		return nil, fmt.Errorf("invalid SYMBOL value: %w", err)
+	}
+
+	defaultNDays := DefaultNDays
+	if file.NDays != 0 {
+		defaultNDays = file.NDays
+	}
+	nDaysStr := getEnvOrDefault("NDAYS", strconv.Itoa(defaultNDays))
 	nDays, err := strconv.Atoi(nDaysStr)
 	if err != nil {
 		return nil, fmt.Errorf("invalid NDAYS value: %w", err)
 	}
+	if nDays, err = boundNDays(nDays); err != nil {
+		return nil, err
+	}
 
-	if apiKey == "" {
-		return nil, fmt.Errorf("API_KEY environment variable is required")
+	symbols, err := resolveSymbols(file.Symbols, nDays)
+	if err != nil {
+		return nil, err
+	}
+
+	allowedSymbols, err := resolveAllowedSymbols(os.Getenv("ALLOWED_SYMBOLS"))
+	if err != nil {
+		return nil, err
+	}
+
+	roundAverage, err := strconv.Atoi(getEnvOrDefault("ROUND_AVERAGE", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ROUND_AVERAGE value: %w", err)
+	}
+	if roundAverage < 0 || roundAverage > MaxRoundAverage {
+		return nil, fmt.Errorf("invalid ROUND_AVERAGE value: must be between 0 and %d, got %d", MaxRoundAverage, roundAverage)
+	}
+
+	tracingExporter := os.Getenv("OTEL_EXPORTER")
+	if tracingExporter != "" && tracingExporter != TracingExporterOTLP && tracingExporter != TracingExporterStdout {
+		return nil, fmt.Errorf("invalid OTEL_EXPORTER value %q: must be %s or %s", tracingExporter, TracingExporterOTLP, TracingExporterStdout)
+	}
+
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(getEnvOrDefault("LOG_LEVEL", DefaultLogLevel))); err != nil {
+		return nil, fmt.Errorf("invalid LOG_LEVEL value: %w", err)
 	}
 
 	return &Config{
-		Port:   port,
-		APIKey: apiKey,
-		Symbol: symbol,
-		NDays:  nDays,
+		ServerConfig:   serverConfig,
+		CacheConfig:    cacheConfig,
+		ClientConfig:   clientConfig,
+		ProviderConfig: providerConfig,
+
+		Symbol:         symbol,
+		NDays:          nDays,
+		Symbols:        symbols,
+		AllowedSymbols: allowedSymbols,
+
+		RoundAverage: roundAverage,
+
+		LogLevel:        logLevel,
+		TracingExporter: tracingExporter,
+	}, nil
+}
+
+// newServerConfig reads the HTTP server settings from the environment or file
+func newServerConfig(file *fileConfig) (ServerConfig, error) {
+	serverReadTimeout, err := parseServerTimeout("SERVER_READ_TIMEOUT", DefaultServerReadTimeout)
+	if err != nil {
+		return ServerConfig{}, err
+	}
+	serverWriteTimeout, err := parseServerTimeout("SERVER_WRITE_TIMEOUT", DefaultServerWriteTimeout)
+	if err != nil {
+		return ServerConfig{}, err
+	}
+	serverIdleTimeout, err := parseServerTimeout("SERVER_IDLE_TIMEOUT", DefaultServerIdleTimeout)
+	if err != nil {
+		return ServerConfig{}, err
+	}
+
+	// REQUESTS_PER_MINUTE already names the upstream limit, so the per-client limit has its own variable
+	clientRequestsPerMinute, err := strconv.Atoi(getEnvOrDefault("CLIENT_REQUESTS_PER_MINUTE", strconv.Itoa(DefaultClientRequestsPerMinute)))
+	if err != nil {
+		return ServerConfig{}, fmt.Errorf("invalid CLIENT_REQUESTS_PER_MINUTE value: %w", err)
+	}
+	if clientRequestsPerMinute < 0 {
+		return ServerConfig{}, fmt.Errorf("invalid CLIENT_REQUESTS_PER_MINUTE value: must not be negative, got %d", clientRequestsPerMinute)
+	}
+
+	trustedProxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return ServerConfig{}, err
+	}
+
+	tlsCertFile, tlsKeyFile, err := resolveTLSFiles(os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE"))
+	if err != nil {
+		return ServerConfig{}, err
+	}
+
+	return ServerConfig{
+		Port:        getEnvOrDefault("PORT", firstNonEmpty(file.Port, DefaultPort)),
+		BindAddress: os.Getenv("BIND_ADDRESS"),
+
+		ServerReadTimeout:  serverReadTimeout,
+		ServerWriteTimeout: serverWriteTimeout,
+		ServerIdleTimeout:  serverIdleTimeout,
+
+		ClientRequestsPerMinute: clientRequestsPerMinute,
+		TrustedProxies:          trustedProxies,
+
+		AllowedOrigins: splitList(os.Getenv("ALLOWED_ORIGINS")),
+		AuthToken:      os.Getenv("AUTH_TOKEN"),
+		TLSCertFile:    tlsCertFile,
+		TLSKeyFile:     tlsKeyFile,
+	}, nil
+}
+
+// newCacheConfig reads the cache settings from the environment or file
+func newCacheConfig(file *fileConfig) (CacheConfig, error) {
+	cacheMaxItems, err := strconv.Atoi(getEnvOrDefault("CACHE_MAX_ITEMS", strconv.Itoa(DefaultCacheMaxItems)))
+	if err != nil {
+		return CacheConfig{}, fmt.Errorf("invalid CACHE_MAX_ITEMS value: %w", err)
+	}
+	if cacheMaxItems < 0 {
+		return CacheConfig{}, fmt.Errorf("invalid CACHE_MAX_ITEMS value: must not be negative, got %d", cacheMaxItems)
+	}
+
+	cacheTTL, err := time.ParseDuration(getEnvOrDefault("CACHE_TTL", firstNonEmpty(file.CacheTTL, DefaultCacheTTL.String())))
+	if err != nil {
+		return CacheConfig{}, fmt.Errorf("invalid CACHE_TTL value: %w", err)
+	}
+	if cacheTTL <= 0 {
+		return CacheConfig{}, fmt.Errorf("invalid CACHE_TTL value: must be positive, got %s", cacheTTL)
+	}
+
+	cacheTTLJitter, err := strconv.Atoi(getEnvOrDefault("CACHE_TTL_JITTER", "0"))
+	if err != nil {
+		return CacheConfig{}, fmt.Errorf("invalid CACHE_TTL_JITTER value: %w", err)
+	}
+	if cacheTTLJitter < 0 || cacheTTLJitter >= 100 {
+		return CacheConfig{}, fmt.Errorf("invalid CACHE_TTL_JITTER value: must be between 0 and 99, got %d", cacheTTLJitter)
+	}
+
+	prefetch, err := strconv.ParseBool(getEnvOrDefault("PREFETCH", "false"))
+	if err != nil {
+		return CacheConfig{}, fmt.Errorf("invalid PREFETCH value: %w", err)
+	}
+
+	refreshAheadWindow, err := time.ParseDuration(getEnvOrDefault("REFRESH_AHEAD_WINDOW", "0s"))
+	if err != nil {
+		return CacheConfig{}, fmt.Errorf("invalid REFRESH_AHEAD_WINDOW value: %w", err)
+	}
+	if refreshAheadWindow < 0 || refreshAheadWindow >= cacheTTL {
+		return CacheConfig{}, fmt.Errorf("invalid REFRESH_AHEAD_WINDOW value: must be between 0 and CACHE_TTL (%s), got %s", cacheTTL, refreshAheadWindow)
+	}
+
+	staleIfError, err := time.ParseDuration(getEnvOrDefault("STALE_IF_ERROR", "0s"))
+	if err != nil {
+		return CacheConfig{}, fmt.Errorf("invalid STALE_IF_ERROR value: %w", err)
+	}
+	if staleIfError < 0 {
+		return CacheConfig{}, fmt.Errorf("invalid STALE_IF_ERROR value: must not be negative, got %s", staleIfError)
+	}
+
+	cacheBackend := getEnvOrDefault("CACHE_BACKEND", DefaultCacheBackend)
+	if cacheBackend != CacheBackendMemory && cacheBackend != CacheBackendRedis {
+		return CacheConfig{}, fmt.Errorf("invalid CACHE_BACKEND value %q: must be %s or %s", cacheBackend, CacheBackendMemory, CacheBackendRedis)
+	}
+
+	return CacheConfig{
+		CacheMaxItems: cacheMaxItems,
+		CacheTTL:      cacheTTL,
+		CacheFile:     os.Getenv("CACHE_FILE"),
+		CacheBackend:  cacheBackend,
+		RedisURL:      getEnvOrDefault("REDIS_URL", DefaultRedisURL),
+		Prefetch:      prefetch,
+
+		CacheTTLJitter:     cacheTTLJitter,
+		StaleIfError:       staleIfError,
+		RefreshAheadWindow: refreshAheadWindow,
+	}, nil
+}
+
+// newClientConfig reads the upstream request settings from the environment
+func newClientConfig() (ClientConfig, error) {
+	maxRetries, err := strconv.Atoi(getEnvOrDefault("MAX_RETRIES", strconv.Itoa(DefaultMaxRetries)))
+	if err != nil {
+		return ClientConfig{}, fmt.Errorf("invalid MAX_RETRIES value: %w", err)
+	}
+	if maxRetries < 0 {
+		return ClientConfig{}, fmt.Errorf("invalid MAX_RETRIES value: must not be negative, got %d", maxRetries)
+	}
+
+	retryBaseDelay, err := time.ParseDuration(getEnvOrDefault("RETRY_BASE_DELAY", DefaultRetryBaseDelay.String()))
+	if err != nil {
+		return ClientConfig{}, fmt.Errorf("invalid RETRY_BASE_DELAY value: %w", err)
+	}
+
+	upstreamTimeout, err := time.ParseDuration(getEnvOrDefault("UPSTREAM_TIMEOUT", DefaultUpstreamTimeout.String()))
+	if err != nil {
+		return ClientConfig{}, fmt.Errorf("invalid UPSTREAM_TIMEOUT value: %w", err)
+	}
+	if upstreamTimeout <= 0 {
+		return ClientConfig{}, fmt.Errorf("invalid UPSTREAM_TIMEOUT value: must be positive, got %s", upstreamTimeout)
+	}
+
+	maxResponseSize, err := strconv.ParseInt(getEnvOrDefault("MAX_RESPONSE_SIZE", strconv.FormatInt(client.DefaultMaxResponseSize, 10)), 10, 64)
+	if err != nil {
+		return ClientConfig{}, fmt.Errorf("invalid MAX_RESPONSE_SIZE value: %w", err)
+	}
+	if maxResponseSize <= 0 {
+		return ClientConfig{}, fmt.Errorf("invalid MAX_RESPONSE_SIZE value: must be positive, got %d", maxResponseSize)
+	}
+
+	requestsPerMinute, err := strconv.Atoi(getEnvOrDefault("REQUESTS_PER_MINUTE", strconv.Itoa(DefaultRequestsPerMinute)))
+	if err != nil {
+		return ClientConfig{}, fmt.Errorf("invalid REQUESTS_PER_MINUTE value: %w", err)
+	}
+	if requestsPerMinute < 0 {
+		return ClientConfig{}, fmt.Errorf("invalid REQUESTS_PER_MINUTE value: must not be negative, got %d", requestsPerMinute)
+	}
+
+	concurrency, err := strconv.Atoi(getEnvOrDefault("CONCURRENCY", strconv.Itoa(DefaultConcurrency)))
+	if err != nil {
+		return ClientConfig{}, fmt.Errorf("invalid CONCURRENCY value: %w", err)
+	}
+	if concurrency <= 0 {
+		return ClientConfig{}, fmt.Errorf("invalid CONCURRENCY value: must be positive, got %d", concurrency)
+	}
+
+	symbolLock, err := strconv.ParseBool(getEnvOrDefault("SYMBOL_LOCK", "true"))
+	if err != nil {
+		return ClientConfig{}, fmt.Errorf("invalid SYMBOL_LOCK value: %w", err)
+	}
+
+	return ClientConfig{
+		MaxRetries:      maxRetries,
+		RetryBaseDelay:  retryBaseDelay,
+		UpstreamTimeout: upstreamTimeout,
+		UserAgent:       getEnvOrDefault("USER_AGENT", DefaultUserAgent+"/"+version.Version),
+		MaxResponseSize: maxResponseSize,
+
+		RequestsPerMinute: requestsPerMinute,
+		Concurrency:       concurrency,
+		SymbolLock:        symbolLock,
+	}, nil
+}
+
+// newProviderConfig reads the provider settings from the environment or file
+func newProviderConfig(file *fileConfig) (ProviderConfig, error) {
+	provider := getEnvOrDefault("PROVIDER", DefaultProvider)
+	if provider != ProviderAlphaVantage && provider != ProviderFinnhub && provider != ProviderMock {
+		return ProviderConfig{}, fmt.Errorf("invalid PROVIDER value %q: must be %s, %s or %s", provider, ProviderAlphaVantage, ProviderFinnhub, ProviderMock)
+	}
+
+	apiKey, err := resolveAPIKey(file.APIKey)
+	if err != nil {
+		return ProviderConfig{}, err
+	}
+	if apiKey == "" && provider != ProviderMock {
+		return ProviderConfig{}, fmt.Errorf("API_KEY or API_KEY_FILE environment variable is required")
+	}
+
+	maxStaleness, err := time.ParseDuration(getEnvOrDefault("MAX_STALENESS", "0s"))
+	if err != nil {
+		return ProviderConfig{}, fmt.Errorf("invalid MAX_STALENESS value: %w", err)
+	}
+	if maxStaleness < 0 {
+		return ProviderConfig{}, fmt.Errorf("invalid MAX_STALENESS value: must not be negative, got %s", maxStaleness)
+	}
+
+	rejectStale, err := strconv.ParseBool(getEnvOrDefault("REJECT_STALE", "false"))
+	if err != nil {
+		return ProviderConfig{}, fmt.Errorf("invalid REJECT_STALE value: %w", err)
+	}
+
+	return ProviderConfig{
+		Provider:     provider,
+		APIKey:       apiKey,
+		MaxStaleness: maxStaleness,
+		RejectStale:  rejectStale,
 	}, nil
 }
 
+// NDaysFor returns the default number of days for symbol: its own setting when
+// it is a watched symbol, otherwise NDays
+func (c *Config) NDaysFor(symbol string) int {
+	for _, s := range c.Symbols {
+		if s.Symbol == symbol {
+			return s.NDays
+		}
+	}
+	return c.NDays
+}
+
+// SymbolAllowed reports whether symbol may be requested under AllowedSymbols
+func (c *Config) SymbolAllowed(symbol string) bool {
+	if len(c.AllowedSymbols) == 0 {
+		return true
+	}
+	for _, allowed := range c.AllowedSymbols {
+		if allowed == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveAllowedSymbols parses the comma-separated ALLOWED_SYMBOLS value,
+// normalizing each symbol to uppercase
+func resolveAllowedSymbols(value string) ([]string, error) {
+	var symbols []string
+	for _, symbol := range splitList(value) {
+		symbol = NormalizeSymbol(symbol)
+		if err := ValidateSymbol(symbol); err != nil {
+			return nil, fmt.Errorf("invalid ALLOWED_SYMBOLS value: %w", err)
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols, nil
+}
+
+// resolveAPIKey returns the API key from API_KEY, the file named by API_KEY_FILE
+// such as a mounted secret, or the config file, in that order of precedence
+func resolveAPIKey(fileKey string) (string, error) {
+	if apiKey := os.Getenv("API_KEY"); apiKey != "" {
+		return apiKey, nil
+	}
+
+	path := os.Getenv("API_KEY_FILE")
+	if path == "" {
+		return fileKey, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid API_KEY_FILE value: %w", err)
+	}
+	apiKey := strings.TrimSpace(string(data))
+	if apiKey == "" {
+		return "", fmt.Errorf("invalid API_KEY_FILE value: %s is empty", path)
+	}
+
+	return apiKey, nil
+}
+
+// parseTrustedProxies parses a comma-separated list of IP addresses and CIDR
+// ranges, treating a bare address as a range holding only that address
+func parseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, item := range splitList(value) {
+		if addr, err := netip.ParseAddr(item); err == nil {
+			proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES value: %q is not an IP address or CIDR range", item)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+// resolveTLSFiles checks that the certificate and key files are given together
+// and can be read, so a misconfigured server fails at startup
+func resolveTLSFiles(certFile, keyFile string) (string, string, error) {
+	if certFile == "" && keyFile == "" {
+		return "", "", nil
+	}
+	if certFile == "" {
+		return "", "", fmt.Errorf("invalid TLS_KEY_FILE value: TLS_CERT_FILE must also be set")
+	}
+	if keyFile == "" {
+		return "", "", fmt.Errorf("invalid TLS_CERT_FILE value: TLS_KEY_FILE must also be set")
+	}
+
+	for _, file := range []struct{ name, path string }{{"TLS_CERT_FILE", certFile}, {"TLS_KEY_FILE", keyFile}} {
+		f, err := os.Open(file.path)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid %s value: %w", file.name, err)
+		}
+		f.Close()
+	}
+
+	return certFile, keyFile, nil
+}
+
+// boundNDays rejects a non-positive number of days and caps it at MaxNDays
+func boundNDays(nDays int) (int, error) {
+	if nDays < 1 {
+		return 0, fmt.Errorf("invalid NDAYS value: must be at least 1, got %d", nDays)
+	}
+	if nDays > MaxNDays {
+		return MaxNDays, nil
+	}
+	return nDays, nil
+}
+
+// NormalizeSymbol returns the canonical form of symbol, trimmed and uppercase,
+// so equivalent spellings pass ValidateSymbol and share cache entries and upstream calls
+func NormalizeSymbol(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
+
+// ValidateSymbol checks that symbol is 1-5 uppercase letters, optionally followed
+// by a dot and a share class letter (e.g. BRK.B)
+func ValidateSymbol(symbol string) error {
+	if !symbolPattern.MatchString(symbol) {
+		return fmt.Errorf("invalid symbol %q: must be 1-5 uppercase letters, optionally with a class suffix such as BRK.B", symbol)
+	}
+	return nil
+}
+
+// parseServerTimeout reads an HTTP server timeout from the environment variable
+// key. Zero is allowed and disables the timeout.
+func parseServerTimeout(key string, defaultValue time.Duration) (time.Duration, error) {
+	timeout, err := time.ParseDuration(getEnvOrDefault(key, defaultValue.String()))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value: %w", key, err)
+	}
+	if timeout < 0 {
+		return 0, fmt.Errorf("invalid %s value: must not be negative, got %s", key, timeout)
+	}
+	return timeout, nil
+}
+
 // getEnvOrDefault returns the value of the environment variable or the default value
 func getEnvOrDefault(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -53,3 +640,24 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return value
 }
+
+// splitList splits a comma-separated value into its trimmed, non-empty elements
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
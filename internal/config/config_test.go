@@ -0,0 +1,292 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestValidateSymbol(t *testing.T) {
+	tests := []struct {
+		symbol  string
+		wantErr bool
+	}{
+		{symbol: "A", wantErr: false},
+		{symbol: "IBM", wantErr: false},
+		{symbol: "GOOGL", wantErr: false},
+		{symbol: "BRK.B", wantErr: false},
+		{symbol: "", wantErr: true},
+		{symbol: "TOOLONG", wantErr: true},
+		{symbol: "aapl", wantErr: true},
+		{symbol: "AA PL", wantErr: true},
+		{symbol: " IBM", wantErr: true},
+		{symbol: "IBM1", wantErr: true},
+		{symbol: "BRK.", wantErr: true},
+		{symbol: "BRK.BB", wantErr: true},
+		{symbol: ".B", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			err := ValidateSymbol(tt.symbol)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSymbol(%q) error = %v, wantErr %v", tt.symbol, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeSymbol(t *testing.T) {
+	tests := []struct {
+		symbol string
+		want   string
+	}{
+		{symbol: "AAPL", want: "AAPL"},
+		{symbol: "aapl", want: "AAPL"},
+		{symbol: " AAPL ", want: "AAPL"},
+		{symbol: "\tbrk.b\n", want: "BRK.B"},
+		{symbol: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		got := NormalizeSymbol(tt.symbol)
+		if got != tt.want {
+			t.Errorf("NormalizeSymbol(%q) = %q, want %q", tt.symbol, got, tt.want)
+		}
+		if again := NormalizeSymbol(got); again != got {
+			t.Errorf("NormalizeSymbol(%q) = %q, want it unchanged", got, again)
+		}
+	}
+}
+
+func TestBoundNDays(t *testing.T) {
+	tests := []struct {
+		nDays   int
+		want    int
+		wantErr bool
+	}{
+		{nDays: -1, wantErr: true},
+		{nDays: 0, wantErr: true},
+		{nDays: 1, want: 1},
+		{nDays: 7, want: 7},
+		{nDays: MaxNDays, want: MaxNDays},
+		{nDays: MaxNDays + 1, want: MaxNDays},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.nDays), func(t *testing.T) {
+			got, err := boundNDays(tt.nDays)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("boundNDays(%d) error = %v, wantErr %v", tt.nDays, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("boundNDays(%d) = %d, want %d", tt.nDays, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWithConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	contents := `
+port: "9090"
+api_key: file-key
+ndays: 10
+cache_ttl: 1h
+symbols:
+  - symbol: AAPL
+    ndays: 30
+  - symbol: MSFT
+`
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"PORT", "API_KEY", "API_KEY_FILE", "SYMBOL", "NDAYS", "CACHE_TTL"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("CONFIG_FILE", path)
+	// Environment variables override the file
+	t.Setenv("PORT", "7070")
+
+	cfg, err := New()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Port != "7070" {
+		t.Errorf("expected PORT to override the file, got %s", cfg.Port)
+	}
+	if cfg.APIKey != "file-key" {
+		t.Errorf("expected APIKey from the file, got %s", cfg.APIKey)
+	}
+	if cfg.CacheTTL != time.Hour {
+		t.Errorf("expected CacheTTL 1h from the file, got %v", cfg.CacheTTL)
+	}
+	// Promoted fields are the fields of the sub-structs
+	if cfg.ServerConfig.Port != cfg.Port || cfg.ProviderConfig.APIKey != cfg.APIKey || cfg.CacheConfig.CacheTTL != cfg.CacheTTL {
+		t.Errorf("expected the sub-structs to hold the settings, got %+v", cfg)
+	}
+	if got := cfg.NDaysFor("AAPL"); got != 30 {
+		t.Errorf("expected 30 days for AAPL, got %d", got)
+	}
+	if got := cfg.NDaysFor("MSFT"); got != 10 {
+		t.Errorf("expected MSFT to default to the global 10 days, got %d", got)
+	}
+	if got := cfg.NDaysFor("IBM"); got != 10 {
+		t.Errorf("expected an unwatched symbol to use the global 10 days, got %d", got)
+	}
+}
+
+func TestResolveAPIKey(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "api-key")
+	if err := os.WriteFile(secret, []byte("  secret-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     string
+		file    string
+		want    string
+		wantErr bool
+	}{
+		{name: "file contents trimmed", file: secret, want: "secret-key"},
+		{name: "env takes precedence", env: "env-key", file: secret, want: "env-key"},
+		{name: "config file fallback", want: "config-key"},
+		{name: "missing file", file: filepath.Join(dir, "missing"), wantErr: true},
+		{name: "empty file", file: empty, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("API_KEY", tt.env)
+			t.Setenv("API_KEY_FILE", tt.file)
+
+			got, err := resolveAPIKey("config-key")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveAPIKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("resolveAPIKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseServerTimeout(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "", want: DefaultServerWriteTimeout},
+		{value: "5m", want: 5 * time.Minute},
+		{value: "0", want: 0},
+		{value: "-1s", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("SERVER_WRITE_TIMEOUT", tt.value)
+
+			got, err := parseServerTimeout("SERVER_WRITE_TIMEOUT", DefaultServerWriteTimeout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseServerTimeout() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseServerTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveAllowedSymbols(t *testing.T) {
+	symbols, err := resolveAllowedSymbols(" aapl, MSFT ,brk.b")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := &Config{AllowedSymbols: symbols}
+	for _, symbol := range []string{"AAPL", "MSFT", "BRK.B"} {
+		if !cfg.SymbolAllowed(symbol) {
+			t.Errorf("expected %s to be allowed, got %v", symbol, symbols)
+		}
+	}
+	if cfg.SymbolAllowed("TSLA") {
+		t.Error("expected TSLA not to be allowed")
+	}
+
+	if _, err := resolveAllowedSymbols("AAPL,TOOLONG"); err == nil {
+		t.Error("expected an error for an invalid symbol")
+	}
+	if symbols, err := resolveAllowedSymbols(""); err != nil || !(&Config{AllowedSymbols: symbols}).SymbolAllowed("TSLA") {
+		t.Errorf("expected every symbol to be allowed without a list, got %v, %v", symbols, err)
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := parseTrustedProxies(" 10.0.0.1, 192.168.0.0/16 ,::1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"10.0.0.1/32", "192.168.0.0/16", "::1/128"}
+	if len(proxies) != len(want) {
+		t.Fatalf("expected %v, got %v", want, proxies)
+	}
+	for i, prefix := range proxies {
+		if prefix.String() != want[i] {
+			t.Errorf("expected %s, got %s", want[i], prefix)
+		}
+	}
+
+	if _, err := parseTrustedProxies("10.0.0.1,proxy.local"); err == nil {
+		t.Error("expected an error for a host name")
+	}
+	if proxies, err := parseTrustedProxies(""); err != nil || proxies != nil {
+		t.Errorf("expected no proxies when unset, got %v, %v", proxies, err)
+	}
+}
+
+func TestResolveTLSFiles(t *testing.T) {
+	dir := t.TempDir()
+	cert := filepath.Join(dir, "tls.crt")
+	key := filepath.Join(dir, "tls.key")
+	for _, path := range []string{cert, key} {
+		if err := os.WriteFile(path, []byte("pem"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		cert    string
+		key     string
+		wantErr bool
+	}{
+		{name: "disabled"},
+		{name: "both files", cert: cert, key: key},
+		{name: "missing key", cert: cert, wantErr: true},
+		{name: "missing cert", key: key, wantErr: true},
+		{name: "unreadable cert", cert: filepath.Join(dir, "missing.crt"), key: key, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotCert, gotKey, err := resolveTLSFiles(tt.cert, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveTLSFiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (gotCert != tt.cert || gotKey != tt.key) {
+				t.Errorf("resolveTLSFiles() = %q, %q, want %q, %q", gotCert, gotKey, tt.cert, tt.key)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SymbolConfig holds the settings of a watched symbol
+type SymbolConfig struct {
+	Symbol string `yaml:"symbol"`
+	// NDays is the default number of days returned for the symbol
+	NDays int `yaml:"ndays"`
+}
+
+// fileConfig is the contents of the file named by CONFIG_FILE. Unset fields
+// fall back to the defaults, and environment variables override all of them.
+type fileConfig struct {
+	Port     string         `yaml:"port"`
+	APIKey   string         `yaml:"api_key"`
+	Symbol   string         `yaml:"symbol"`
+	NDays    int            `yaml:"ndays"`
+	CacheTTL string         `yaml:"cache_ttl"`
+	Symbols  []SymbolConfig `yaml:"symbols"`
+}
+
+// loadFile reads a YAML or JSON config file
+func loadFile(path string) (*fileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CONFIG_FILE: %w", err)
+	}
+
+	// JSON is a subset of YAML, so one decoder handles both formats
+	var file fileConfig
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing CONFIG_FILE %s: %w", path, err)
+	}
+
+	return &file, nil
+}
+
+// resolveSymbols validates the watched symbols, defaulting their days to nDays
+func resolveSymbols(symbols []SymbolConfig, nDays int) ([]SymbolConfig, error) {
+	resolved := make([]SymbolConfig, 0, len(symbols))
+	for _, s := range symbols {
+		if err := ValidateSymbol(s.Symbol); err != nil {
+			return nil, fmt.Errorf("invalid symbols entry in CONFIG_FILE: %w", err)
+		}
+
+		if s.NDays == 0 {
+			s.NDays = nDays
+		}
+		days, err := boundNDays(s.NDays)
+		if err != nil {
+			return nil, fmt.Errorf("invalid symbols entry %s in CONFIG_FILE: %w", s.Symbol, err)
+		}
+		s.NDays = days
+
+		resolved = append(resolved, s)
+	}
+	return resolved, nil
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// namespace prefixes every metric exported by the service
+const namespace = "stockticker"
+
+var (
+	// RequestsTotal counts HTTP requests by response status and requested symbol
+	RequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "Total number of HTTP requests by status code and symbol.",
+	}, []string{"status", "symbol"})
+
+	// RequestDuration observes HTTP request latency by path
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"path"})
+
+	// CacheHits counts stock data lookups served from the cache
+	CacheHits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_hits_total",
+		Help:      "Total number of stock data cache hits.",
+	})
+
+	// CacheMisses counts stock data lookups that required an upstream fetch
+	CacheMisses = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_misses_total",
+		Help:      "Total number of stock data cache misses.",
+	})
+
+	// UpstreamDuration observes the latency of calls to the stock data provider by outcome
+	UpstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Stock data provider request latency in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider", "outcome"})
+)
+
+// Outcome returns the outcome label for an upstream call that returned err
+func Outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
@@ -0,0 +1,68 @@
+// Package requestid carries a per-request identifier through contexts and log lines
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+)
+
+// Header is the HTTP header used to receive and echo request IDs
+const Header = "X-Request-ID"
+
+// logKey is the log attribute under which the request ID is recorded
+const logKey = "request_id"
+
+// contextKey is unexported so only this package can set the request ID
+type contextKey struct{}
+
+// New returns a random version 4 UUID
+func New() string {
+	var b [16]byte
+	// crypto/rand.Read never returns an error on supported platforms
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// NewContext returns a copy of ctx carrying id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or an empty string if there is none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// LogHandler wraps a slog.Handler, adding the request ID from the context to
+// every record logged with one of the Context logging methods
+type LogHandler struct {
+	slog.Handler
+}
+
+// NewLogHandler returns a LogHandler that writes to next
+func NewLogHandler(next slog.Handler) *LogHandler {
+	return &LogHandler{Handler: next}
+}
+
+// Handle adds the request ID, if any, before passing the record on
+func (h *LogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := FromContext(ctx); id != "" {
+		record.AddAttrs(slog.String(logKey, id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs returns a LogHandler whose wrapped handler includes attrs
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a LogHandler whose wrapped handler nests attributes under name
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,41 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	first, second := New(), New()
+	if !uuidPattern.MatchString(first) {
+		t.Errorf("expected a version 4 UUID, got %q", first)
+	}
+	if first == second {
+		t.Errorf("expected distinct IDs, got %q twice", first)
+	}
+}
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(slog.NewJSONHandler(&buf, nil))).With("component", "test")
+
+	logger.InfoContext(NewContext(context.Background(), "abc-123"), "with ID")
+	logger.InfoContext(context.Background(), "without ID")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %d", len(lines))
+	}
+	if !strings.Contains(lines[0], `"request_id":"abc-123"`) || !strings.Contains(lines[0], `"component":"test"`) {
+		t.Errorf("expected request ID and component in %s", lines[0])
+	}
+	if strings.Contains(lines[1], "request_id") {
+		t.Errorf("expected no request ID in %s", lines[1])
+	}
+}
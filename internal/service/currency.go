@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/client"
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+const (
+	// BaseCurrency is the currency of the prices returned by the providers
+	BaseCurrency = "USD"
+	// exchangeRateTTL is how long an exchange rate is cached
+	exchangeRateTTL = 5 * time.Minute
+)
+
+// ErrCurrencyUnsupported is returned when a conversion is requested from a provider without exchange rates
+var ErrCurrencyUnsupported = errors.New("currency conversion is not supported by the configured provider")
+
+// ConvertCurrency returns a copy of stockData with its prices and price statistics
+// converted to currency. The original is left untouched since it may be shared
+// through the cache.
+func (s *StockService) ConvertCurrency(ctx context.Context, stockData *models.StockData, currency string) (*models.StockData, error) {
+	from := stockData.Currency
+	if from == "" {
+		from = BaseCurrency
+	}
+	if currency == "" || currency == from {
+		return stockData, nil
+	}
+
+	rate, err := s.exchangeRate(ctx, from, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := *stockData
+	converted.Currency = currency
+	converted.Prices = make([]models.StockPrice, len(stockData.Prices))
+	for i, price := range stockData.Prices {
+		price.Open *= rate
+		price.High *= rate
+		price.Low *= rate
+		price.Close *= rate
+		converted.Prices[i] = price
+	}
+	converted.Average = s.roundAverage(converted.Average * rate)
+	converted.Median *= rate
+	converted.Min *= rate
+	converted.Max *= rate
+	converted.StdDev *= rate
+
+	return &converted, nil
+}
+
+// exchangeRate returns the rate between two currencies, cached for exchangeRateTTL
+func (s *StockService) exchangeRate(ctx context.Context, from, to string) (float64, error) {
+	cacheKey := fmt.Sprintf("fx:%s:%s", from, to)
+	if value, found := s.cache.Get(cacheKey); found {
+		if rate, ok := value.(float64); ok {
+			return rate, nil
+		}
+	}
+
+	provider, ok := s.client.(client.ExchangeRateProvider)
+	if !ok {
+		return 0, ErrCurrencyUnsupported
+	}
+
+	rate, err := provider.GetExchangeRate(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	s.cache.Set(cacheKey, rate, exchangeRateTTL)
+	return rate, nil
+}
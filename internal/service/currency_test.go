@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/saedabdu/stockticker/internal/cache"
+	"github.com/saedabdu/stockticker/internal/config"
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+func TestConvertCurrency(t *testing.T) {
+	provider := &exchangeRateProvider{rate: 0.5}
+	service := New(&config.Config{}, provider, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	original := &models.StockData{
+		Symbol:   "AAPL",
+		Currency: BaseCurrency,
+		Prices:   []models.StockPrice{{Date: "2023-01-03", Open: 10, High: 12, Low: 8, Close: 11, Volume: 1000}},
+		Average:  11,
+		Median:   11,
+		Min:      11,
+		Max:      11,
+
+		PercentChange: 5,
+	}
+
+	for i := 0; i < 2; i++ {
+		converted, err := service.ConvertCurrency(context.Background(), original, "EUR")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if converted.Currency != "EUR" {
+			t.Errorf("expected Currency EUR, got %s", converted.Currency)
+		}
+		want := models.StockPrice{Date: "2023-01-03", Open: 5, High: 6, Low: 4, Close: 5.5, Volume: 1000}
+		if converted.Prices[0] != want {
+			t.Errorf("expected price %+v, got %+v", want, converted.Prices[0])
+		}
+		if converted.Average != 5.5 || converted.Min != 5.5 || converted.Max != 5.5 {
+			t.Errorf("expected converted statistics, got %+v", converted)
+		}
+		if converted.PercentChange != 5 {
+			t.Errorf("expected PercentChange to be unchanged, got %v", converted.PercentChange)
+		}
+	}
+
+	if original.Currency != BaseCurrency || original.Prices[0].Close != 11 {
+		t.Errorf("expected original data to be left untouched, got %+v", original)
+	}
+	if provider.calls != 1 {
+		t.Errorf("expected the exchange rate to be cached after 1 call, got %d calls", provider.calls)
+	}
+}
+
+func TestConvertCurrencyUnsupportedProvider(t *testing.T) {
+	service := New(&config.Config{}, &stubProvider{}, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	data := &models.StockData{Symbol: "AAPL", Currency: BaseCurrency}
+
+	if _, err := service.ConvertCurrency(context.Background(), data, "EUR"); !errors.Is(err, ErrCurrencyUnsupported) {
+		t.Errorf("expected ErrCurrencyUnsupported, got %v", err)
+	}
+
+	converted, err := service.ConvertCurrency(context.Background(), data, BaseCurrency)
+	if err != nil || converted != data {
+		t.Errorf("expected no conversion to the base currency, got %v, %v", converted, err)
+	}
+}
+
+// exchangeRateProvider is a stubProvider that also returns a fixed exchange rate
+type exchangeRateProvider struct {
+	stubProvider
+	rate float64
+}
+
+func (p *exchangeRateProvider) GetExchangeRate(ctx context.Context, from, to string) (float64, error) {
+	p.calls++
+	return p.rate, nil
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+// ErrStaleData is returned when the provider's data is older than MaxStaleness
+// and RejectStale is set
+var ErrStaleData = errors.New("stock data is stale")
+
+// lastRefreshedLayouts are the formats of LastRefreshed: a timestamp for
+// intraday series and a date for daily and longer ones
+var lastRefreshedLayouts = []string{"2006-01-02 15:04:05", dateLayout}
+
+// checkFreshness compares the provider's LastRefreshed with MaxStaleness. Stale
+// data is rejected with ErrStaleData when RejectStale is set, and otherwise
+// returned as a copy marked Stale, leaving the cached original untouched.
+func (s *StockService) checkFreshness(ctx context.Context, stockData *models.StockData) (*models.StockData, error) {
+	if s.config.MaxStaleness == 0 {
+		return stockData, nil
+	}
+
+	refreshed, ok := lastRefreshedTime(stockData)
+	if !ok {
+		s.logger.DebugContext(ctx, "cannot check freshness of stock data",
+			"symbol", stockData.Symbol, "last_refreshed", stockData.LastRefreshed)
+		return stockData, nil
+	}
+
+	age := time.Since(refreshed)
+	if age <= s.config.MaxStaleness {
+		return stockData, nil
+	}
+
+	if s.config.RejectStale {
+		return nil, fmt.Errorf("%w: %s was last refreshed %s, %s ago", ErrStaleData,
+			stockData.Symbol, stockData.LastRefreshed, age.Round(time.Minute))
+	}
+
+	result := *stockData
+	result.Stale = true
+	return &result, nil
+}
+
+// lastRefreshedTime parses LastRefreshed in the series' time zone. A date is
+// taken as midnight at the start of that day.
+func lastRefreshedTime(stockData *models.StockData) (time.Time, bool) {
+	location := time.UTC
+	if stockData.TimeZone != "" {
+		if loc, err := time.LoadLocation(stockData.TimeZone); err == nil {
+			location = loc
+		}
+	}
+
+	for _, layout := range lastRefreshedLayouts {
+		if t, err := time.ParseInLocation(layout, stockData.LastRefreshed, location); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
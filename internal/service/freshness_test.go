@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/cache"
+	"github.com/saedabdu/stockticker/internal/client"
+	"github.com/saedabdu/stockticker/internal/config"
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+func TestCheckFreshness(t *testing.T) {
+	recent := time.Now().UTC().Add(-time.Hour).Format("2006-01-02 15:04:05")
+
+	tests := []struct {
+		name          string
+		lastRefreshed string
+		maxStaleness  time.Duration
+		rejectStale   bool
+		wantStale     bool
+		wantErr       error
+	}{
+		{name: "check disabled", lastRefreshed: "2023-01-03", wantStale: false},
+		{name: "old date", lastRefreshed: "2023-01-03", maxStaleness: 72 * time.Hour, wantStale: true},
+		{name: "recent timestamp", lastRefreshed: recent, maxStaleness: 2 * time.Hour, wantStale: false},
+		{name: "old timestamp", lastRefreshed: recent, maxStaleness: 30 * time.Minute, wantStale: true},
+		{name: "rejected", lastRefreshed: "2023-01-03", maxStaleness: 72 * time.Hour, rejectStale: true, wantErr: ErrStaleData},
+		{name: "unparseable", lastRefreshed: "yesterday", maxStaleness: time.Hour, wantStale: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &stubProvider{
+				response: &models.AlphaVantageResponse{
+					MetaData: models.MetaData{LastRefreshed: tt.lastRefreshed, TimeZone: "UTC"},
+					TimeSeries: map[string]models.DailyPrice{
+						"2023-01-03": {Open: "150.10", High: "150.10", Low: "150.10", Close: "150.10", Volume: "1000"},
+					},
+				},
+			}
+			cfg := &config.Config{CacheConfig: config.CacheConfig{CacheTTL: time.Minute}, ProviderConfig: config.ProviderConfig{MaxStaleness: tt.maxStaleness, RejectStale: tt.rejectStale}}
+			store := cache.New(0)
+			service := New(cfg, provider, store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			q := Query{Symbol: "AAPL", Days: 7, Interval: client.IntervalDaily}
+
+			// The second call is served from the cache and must be checked too
+			for i := 0; i < 2; i++ {
+				result, err := service.GetStockData(context.Background(), q)
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("expected %v, got %v", tt.wantErr, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if result.Stale != tt.wantStale {
+					t.Errorf("expected stale %v, got %v", tt.wantStale, result.Stale)
+				}
+			}
+
+			if cached, found := store.Get(q.cacheKey()); found && cached.(*models.StockData).Stale {
+				t.Error("expected the cached data to be left unmarked")
+			}
+		})
+	}
+}
+
+func TestLastRefreshedTime(t *testing.T) {
+	tests := []struct {
+		lastRefreshed string
+		timeZone      string
+		want          time.Time
+	}{
+		{lastRefreshed: "2023-01-06", timeZone: "UTC", want: time.Date(2023, 1, 6, 0, 0, 0, 0, time.UTC)},
+		{lastRefreshed: "2023-01-06 16:00:00", timeZone: "UTC", want: time.Date(2023, 1, 6, 16, 0, 0, 0, time.UTC)},
+		{lastRefreshed: "2023-01-06 16:00:00", timeZone: "US/Eastern", want: time.Date(2023, 1, 6, 21, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		got, ok := lastRefreshedTime(&models.StockData{LastRefreshed: tt.lastRefreshed, TimeZone: tt.timeZone})
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("lastRefreshedTime(%q, %q) = %v, %v, want %v", tt.lastRefreshed, tt.timeZone, got, ok, tt.want)
+		}
+	}
+}
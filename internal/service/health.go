@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/client"
+	"github.com/saedabdu/stockticker/internal/config"
+)
+
+const (
+	// upstreamCheckTTL is how long the outcome of an upstream check, or a successful
+	// fetch, is trusted before the provider is probed again
+	upstreamCheckTTL = 30 * time.Second
+	// upstreamCheckKey coalesces concurrent probes; it cannot collide with a cache key
+	upstreamCheckKey = "upstream-check"
+)
+
+// CheckUpstream reports whether the provider is reachable. A fetch that succeeded
+// within upstreamCheckTTL is taken as proof; otherwise the provider is probed
+// with a one-day request for the configured symbol. The probe's outcome is reused
+// for upstreamCheckTTL so frequent health checks don't use up the rate limit.
+// Rate limit and unknown symbol errors count as reachable since the provider answered.
+func (s *StockService) CheckUpstream(ctx context.Context) error {
+	s.healthMu.Lock()
+	if time.Since(s.lastUpstreamSuccess) < upstreamCheckTTL {
+		s.healthMu.Unlock()
+		return nil
+	}
+	if time.Since(s.lastUpstreamCheck) < upstreamCheckTTL {
+		err := s.lastUpstreamErr
+		s.healthMu.Unlock()
+		return err
+	}
+	s.healthMu.Unlock()
+
+	// The probe runs without the caller's cancellation so its outcome can be reused
+	_, err, _ := s.group.Do(upstreamCheckKey, func() (interface{}, error) {
+		probeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.upstreamTimeout())
+		defer cancel()
+
+		_, err := s.client.GetStockData(probeCtx, s.config.Symbol, 1, client.IntervalDaily)
+		if errors.Is(err, client.ErrRateLimited) || errors.Is(err, client.ErrInvalidSymbol) {
+			err = nil
+		}
+		if err != nil {
+			s.logger.WarnContext(ctx, "upstream check failed", "error", err)
+		}
+
+		s.healthMu.Lock()
+		s.lastUpstreamCheck = time.Now()
+		s.lastUpstreamErr = err
+		s.healthMu.Unlock()
+		return nil, err
+	})
+	return err
+}
+
+// recordUpstreamSuccess notes a successful fetch so health checks need not probe
+// the provider. It is called by recordFetch.
+func (s *StockService) recordUpstreamSuccess() {
+	s.healthMu.Lock()
+	s.lastUpstreamSuccess = time.Now()
+	s.healthMu.Unlock()
+}
+
+// upstreamTimeout bounds an upstream probe, falling back to the default when unset
+func (s *StockService) upstreamTimeout() time.Duration {
+	if s.config.UpstreamTimeout > 0 {
+		return s.config.UpstreamTimeout
+	}
+	return config.DefaultUpstreamTimeout
+}
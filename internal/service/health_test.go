@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/cache"
+	"github.com/saedabdu/stockticker/internal/client"
+	"github.com/saedabdu/stockticker/internal/config"
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+func TestCheckUpstream(t *testing.T) {
+	provider := &stubProvider{err: client.ErrUpstreamUnavailable}
+	service := New(&config.Config{Symbol: "IBM", CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}, provider, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for i := 0; i < 2; i++ {
+		if err := service.CheckUpstream(context.Background()); !errors.Is(err, client.ErrUpstreamUnavailable) {
+			t.Fatalf("expected the upstream error, got %v", err)
+		}
+	}
+	// The failed probe is reused rather than repeated
+	if provider.calls != 1 {
+		t.Errorf("expected 1 probe, got %d", provider.calls)
+	}
+
+	// A rate limited provider is reachable
+	provider.err = client.ErrRateLimited
+	service.lastUpstreamCheck = time.Time{}
+	if err := service.CheckUpstream(context.Background()); err != nil {
+		t.Errorf("expected a rate limited provider to count as reachable, got %v", err)
+	}
+}
+
+func TestCheckUpstreamTrustsRecentFetch(t *testing.T) {
+	provider := &stubProvider{
+		response: &models.AlphaVantageResponse{
+			TimeSeries: map[string]models.DailyPrice{
+				"2023-01-03": {Open: "1", High: "1", Low: "1", Close: "1", Volume: "1"},
+			},
+		},
+	}
+	service := New(&config.Config{Symbol: "IBM", CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}, provider, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, err := service.GetStockData(context.Background(), Query{Symbol: "AAPL", Days: 1, Interval: client.IntervalDaily}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := service.CheckUpstream(context.Background()); err != nil {
+		t.Errorf("expected the provider to be reachable, got %v", err)
+	}
+	if provider.calls != 1 {
+		t.Errorf("expected no probe after a recent fetch, got %d provider calls", provider.calls)
+	}
+}
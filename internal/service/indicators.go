@@ -0,0 +1,139 @@
+package service
+
+import "github.com/saedabdu/stockticker/pkg/models"
+
+// MovingAverage returns the simple moving average of the field prices, the
+// closes by default, over window days. Prices must be sorted newest first, as
+// returned by GetStockData, and the series is in the same order. Dates without
+// window days of history in prices are omitted, so the series has
+// len(prices)-window+1 points.
+func MovingAverage(prices []models.StockPrice, window int, field models.PriceField) []models.SeriesPoint {
+	// Walk the closes oldest first so each average covers the preceding days
+	closes := make([]float64, len(prices))
+	for i, price := range prices {
+		closes[len(prices)-1-i] = price.Value(field)
+	}
+
+	averages := simpleMovingAverage(closes, window)
+	series := make([]models.SeriesPoint, len(averages))
+	for i, average := range averages {
+		// averages[i] ends at closes[i+window-1], which is prices[len(averages)-1-i]
+		series[len(averages)-1-i] = models.SeriesPoint{
+			Date:  prices[len(averages)-1-i].Date,
+			Value: average,
+		}
+	}
+	return series
+}
+
+// ExponentialMovingAverage returns the exponential moving average of the field
+// prices, the closes by default, over window days with the smoothing factor
+// 2/(window+1), seeded with the simple average of the oldest window prices.
+// Prices must be sorted newest first, as returned by GetStockData, and the
+// series is in the same order with len(prices)-window+1 points.
+func ExponentialMovingAverage(prices []models.StockPrice, window int, field models.PriceField) []models.SeriesPoint {
+	// Walk the prices oldest first so each average builds on the preceding days
+	values := make([]float64, len(prices))
+	for i, price := range prices {
+		values[len(prices)-1-i] = price.Value(field)
+	}
+
+	averages := exponentialMovingAverage(values, window)
+	series := make([]models.SeriesPoint, len(averages))
+	for i, average := range averages {
+		// averages[i] ends at values[i+window-1], which is prices[len(averages)-1-i]
+		series[len(averages)-1-i] = models.SeriesPoint{
+			Date:  prices[len(averages)-1-i].Date,
+			Value: average,
+		}
+	}
+	return series
+}
+
+// Returns returns the day-over-day percentage change of the field prices, the
+// closes by default. Prices must be sorted newest first, as returned by
+// GetStockData, and the series is in the same order. The oldest date has no
+// prior price and is omitted, as are dates whose prior price is zero.
+func Returns(prices []models.StockPrice, field models.PriceField) []models.SeriesPoint {
+	series := []models.SeriesPoint{}
+	for i := 0; i+1 < len(prices); i++ {
+		prior := prices[i+1].Value(field)
+		if prior == 0 {
+			continue
+		}
+		series = append(series, models.SeriesPoint{
+			Date:  prices[i].Date,
+			Value: percentChange(prior, prices[i].Value(field)),
+		})
+	}
+	return series
+}
+
+// VWAP returns the volume-weighted average of the field prices, the closes by
+// default, sum(price*volume)/sum(volume). The second result is false when the
+// total volume is zero and the average is undefined.
+func VWAP(prices []models.StockPrice, field models.PriceField) (float64, bool) {
+	var weighted float64
+	var volume int64
+	for _, price := range prices {
+		weighted += price.Value(field) * float64(price.Volume)
+		volume += price.Volume
+	}
+
+	if volume == 0 {
+		return 0, false
+	}
+	return weighted / float64(volume), true
+}
+
+// DailyChange returns the percentage change from the second newest to the newest
+// close. Prices must be sorted newest first. The second result is false when
+// there are fewer than two prices or the older close is zero.
+func DailyChange(prices []models.StockPrice) (float64, bool) {
+	if len(prices) < 2 || prices[1].Close == 0 {
+		return 0, false
+	}
+	return percentChange(prices[1].Close, prices[0].Close), true
+}
+
+// Extremes returns the prices with the highest and lowest close. Prices must be
+// sorted newest first; ties resolve to the earliest date. The third result is
+// false when prices is empty.
+func Extremes(prices []models.StockPrice) (high, low models.StockPrice, ok bool) {
+	if len(prices) == 0 {
+		return models.StockPrice{}, models.StockPrice{}, false
+	}
+
+	// Walk oldest first and only replace on a strictly better close
+	high, low = prices[len(prices)-1], prices[len(prices)-1]
+	for i := len(prices) - 2; i >= 0; i-- {
+		if prices[i].Close > high.Close {
+			high = prices[i]
+		}
+		if prices[i].Close < low.Close {
+			low = prices[i]
+		}
+	}
+	return high, low, true
+}
+
+// Correlation returns the Pearson correlation of the closes of a and b on the
+// dates present in both, along with the number of such dates. The last result
+// is false when fewer than two dates align or either series is constant over them.
+func Correlation(a, b []models.StockPrice) (float64, int, bool) {
+	closes := make(map[string]float64, len(b))
+	for _, price := range b {
+		closes[price.Date] = price.Close
+	}
+
+	var x, y []float64
+	for _, price := range a {
+		if other, ok := closes[price.Date]; ok {
+			x = append(x, price.Close)
+			y = append(y, other)
+		}
+	}
+
+	coefficient, ok := pearson(x, y)
+	return coefficient, len(x), ok
+}
@@ -0,0 +1,45 @@
+package service
+
+import "sync"
+
+// symbolLock serializes the upstream fetches of one symbol
+type symbolLock struct {
+	mu sync.Mutex
+	// refs counts the holders and waiters, guarded by StockService.symbolLocksMu
+	refs int
+}
+
+// lockSymbol blocks until no other upstream fetch of symbol is running, whatever
+// its window, interval or code path, and returns the function releasing the lock.
+// Entries are removed from the lock map once nothing holds or waits on them.
+// When SymbolLock is disabled it returns immediately.
+func (s *StockService) lockSymbol(symbol string) (unlock func()) {
+	if !s.config.SymbolLock {
+		return func() {}
+	}
+
+	s.symbolLocksMu.Lock()
+	if s.symbolLocks == nil {
+		s.symbolLocks = make(map[string]*symbolLock)
+	}
+	lock, ok := s.symbolLocks[symbol]
+	if !ok {
+		lock = &symbolLock{}
+		s.symbolLocks[symbol] = lock
+	}
+	lock.refs++
+	s.symbolLocksMu.Unlock()
+
+	lock.mu.Lock()
+
+	return func() {
+		lock.mu.Unlock()
+
+		s.symbolLocksMu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(s.symbolLocks, symbol)
+		}
+		s.symbolLocksMu.Unlock()
+	}
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/cache"
+	"github.com/saedabdu/stockticker/internal/client"
+	"github.com/saedabdu/stockticker/internal/config"
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+func TestSymbolLockSerializesFetches(t *testing.T) {
+	provider := &concurrencyProvider{}
+	service := New(&config.Config{CacheConfig: config.CacheConfig{CacheTTL: time.Minute}, ClientConfig: config.ClientConfig{SymbolLock: true}}, provider, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// Distinct windows of one symbol have distinct cache keys, so singleflight does not coalesce them
+	var wg sync.WaitGroup
+	for days := 1; days <= 5; days++ {
+		wg.Add(1)
+		go func(days int) {
+			defer wg.Done()
+			if _, err := service.GetStockData(context.Background(), Query{Symbol: "AAPL", Days: days, Interval: client.IntervalDaily}); err != nil {
+				t.Errorf("days %d: unexpected error: %v", days, err)
+			}
+		}(days)
+	}
+	wg.Wait()
+
+	if got := provider.maxInFlight.Load(); got != 1 {
+		t.Errorf("expected 1 fetch of AAPL at a time, got %d", got)
+	}
+
+	service.symbolLocksMu.Lock()
+	defer service.symbolLocksMu.Unlock()
+	if len(service.symbolLocks) != 0 {
+		t.Errorf("expected unused locks to be removed, got %d", len(service.symbolLocks))
+	}
+}
+
+func TestSymbolLockAllowsOtherSymbols(t *testing.T) {
+	service := New(&config.Config{ClientConfig: config.ClientConfig{SymbolLock: true}}, nil, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	unlock := service.lockSymbol("AAPL")
+	defer unlock()
+
+	done := make(chan struct{})
+	go func() {
+		service.lockSymbol("MSFT")()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected MSFT not to wait for the AAPL lock")
+	}
+}
+
+func TestSymbolLockRechecksCache(t *testing.T) {
+	provider := &stubProvider{
+		response: &models.AlphaVantageResponse{
+			TimeSeries: map[string]models.DailyPrice{
+				"2023-01-03": {Open: "150.10", High: "150.10", Low: "150.10", Close: "150.10", Volume: "1000"},
+			},
+		},
+	}
+	service := New(&config.Config{CacheConfig: config.CacheConfig{CacheTTL: time.Minute}, ClientConfig: config.ClientConfig{SymbolLock: true}}, provider, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	query := Query{Symbol: "AAPL", Days: 7, Interval: client.IntervalDaily}
+	key := query.cacheKey()
+
+	// Both callers missed the cache; whichever takes the lock second finds the first one's result
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := service.fetchAndCache(context.Background(), query, key, false); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if provider.calls != 1 {
+		t.Errorf("expected 1 provider call, got %d", provider.calls)
+	}
+}
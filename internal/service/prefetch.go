@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/client"
+)
+
+// Prefetch fetches the default daily window of the configured symbol and every
+// watched symbol into the cache, so the first requests after startup do not wait
+// on the provider. Failures are logged and do not stop the remaining symbols.
+func (s *StockService) Prefetch(ctx context.Context) {
+	s.PrefetchSymbols(ctx, s.prefetchSymbols())
+}
+
+// PrefetchSymbols fetches the default daily window of each symbol into the cache,
+// the same data a /stocks request without parameters would fetch. Symbols are
+// fetched one at a time to stay within the upstream rate limit. Results are
+// returned in the same order as symbols, without Data to avoid holding it twice.
+func (s *StockService) PrefetchSymbols(ctx context.Context, symbols []string) []SymbolResult {
+	results := make([]SymbolResult, len(symbols))
+	for i, symbol := range symbols {
+		results[i].Symbol = symbol
+		if ctx.Err() != nil {
+			results[i].Err = ctx.Err()
+			continue
+		}
+
+		start := time.Now()
+		q := Query{Symbol: symbol, Days: s.config.NDaysFor(symbol), Interval: client.IntervalDaily}
+		if _, err := s.GetStockData(ctx, q); err != nil {
+			s.logger.WarnContext(ctx, "error prefetching stock data", "symbol", symbol, "days", q.Days, "error", err)
+			results[i].Err = err
+			continue
+		}
+		s.logger.InfoContext(ctx, "prefetched stock data",
+			"symbol", symbol, "days", q.Days, "duration_ms", time.Since(start).Milliseconds())
+	}
+	return results
+}
+
+// prefetchSymbols returns the configured symbol followed by the watched symbols, without duplicates
+func (s *StockService) prefetchSymbols() []string {
+	seen := map[string]bool{s.config.Symbol: true}
+	symbols := []string{s.config.Symbol}
+	for _, watched := range s.config.Symbols {
+		if !seen[watched.Symbol] {
+			seen[watched.Symbol] = true
+			symbols = append(symbols, watched.Symbol)
+		}
+	}
+	return symbols
+}
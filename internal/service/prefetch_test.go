@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/cache"
+	"github.com/saedabdu/stockticker/internal/client"
+	"github.com/saedabdu/stockticker/internal/config"
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+func TestPrefetch(t *testing.T) {
+	provider := &stubProvider{
+		response: &models.AlphaVantageResponse{
+			TimeSeries: map[string]models.DailyPrice{
+				"2023-01-03": {Open: "150.10", High: "150.10", Low: "150.10", Close: "150.10", Volume: "1000"},
+			},
+		},
+	}
+	cfg := &config.Config{
+		Symbol:      "IBM",
+		NDays:       7,
+		Symbols:     []config.SymbolConfig{{Symbol: "IBM", NDays: 7}, {Symbol: "MSFT", NDays: 30}},
+		CacheConfig: config.CacheConfig{CacheTTL: time.Minute},
+	}
+	service := New(cfg, provider, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	service.Prefetch(context.Background())
+
+	if provider.calls != 2 {
+		t.Errorf("expected 2 provider calls, got %d", provider.calls)
+	}
+
+	// Requests for the default windows are now served from the cache
+	for _, q := range []Query{
+		{Symbol: "IBM", Days: 7, Interval: client.IntervalDaily},
+		{Symbol: "MSFT", Days: 30, Interval: client.IntervalDaily},
+	} {
+		if _, err := service.GetStockData(context.Background(), q); err != nil {
+			t.Fatalf("unexpected error for %s: %v", q.Symbol, err)
+		}
+	}
+	if provider.calls != 2 {
+		t.Errorf("expected cached results after prefetch, got %d provider calls", provider.calls)
+	}
+}
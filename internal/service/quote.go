@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/saedabdu/stockticker/internal/client"
+	"github.com/saedabdu/stockticker/internal/config"
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+// ErrQuotesUnsupported is returned when quotes are requested from a provider without them
+var ErrQuotesUnsupported = errors.New("quotes are not supported by the configured provider")
+
+// QuoteResult holds the outcome of fetching a single symbol's quote
+type QuoteResult struct {
+	Symbol string
+	Quote  *models.Quote
+	Err    error
+}
+
+// quoteCacheKey returns the cache key of symbol's quote. Symbols are uppercase,
+// so the key cannot collide with a symbol's stock data prefix.
+func quoteCacheKey(symbol string) string {
+	return "quote:" + symbol
+}
+
+// GetQuote returns the latest quote of symbol, cached for CacheTTL
+func (s *StockService) GetQuote(ctx context.Context, symbol string) (*models.Quote, error) {
+	symbol = config.NormalizeSymbol(symbol)
+	if err := s.checkAllowed(symbol); err != nil {
+		return nil, err
+	}
+
+	cacheKey := quoteCacheKey(symbol)
+	if value, found := s.cache.Get(cacheKey); found {
+		if quote, ok := value.(*models.Quote); ok {
+			return quote, nil
+		}
+	}
+
+	provider, ok := s.client.(client.QuoteProvider)
+	if !ok {
+		return nil, ErrQuotesUnsupported
+	}
+
+	unlock := s.lockSymbol(symbol)
+	defer unlock()
+	// Another request may have fetched the quote while this one waited for the lock
+	if value, found := s.cache.Get(cacheKey); found {
+		if quote, ok := value.(*models.Quote); ok {
+			return quote, nil
+		}
+	}
+
+	quote, err := provider.GetGlobalQuote(ctx, symbol)
+	s.recordFetch(symbol, err)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.Set(cacheKey, quote, s.config.CacheTTL)
+	return quote, nil
+}
+
+// GetQuotes retrieves the quotes of several symbols, at most Concurrency at a
+// time. Results are returned in the same order as symbols; a failure for one
+// symbol is reported in its result and does not affect the others.
+func (s *StockService) GetQuotes(ctx context.Context, symbols []string) ([]QuoteResult, error) {
+	if _, ok := s.client.(client.QuoteProvider); !ok {
+		return nil, ErrQuotesUnsupported
+	}
+
+	limit := s.config.Concurrency
+	if limit <= 0 {
+		limit = config.DefaultConcurrency
+	}
+	sem := make(chan struct{}, limit)
+
+	results := make([]QuoteResult, len(symbols))
+	var wg sync.WaitGroup
+	for i, symbol := range symbols {
+		wg.Add(1)
+		go func(i int, symbol string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			quote, err := s.GetQuote(ctx, symbol)
+			results[i] = QuoteResult{Symbol: symbol, Quote: quote, Err: err}
+		}(i, symbol)
+	}
+	wg.Wait()
+
+	return results, nil
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+// trackedQuery is a query eligible for refresh-ahead
+type trackedQuery struct {
+	query Query
+	// lastRequested is when a client last asked for the query
+	lastRequested time.Time
+	// expiresAt is when the cached result expires
+	expiresAt time.Time
+}
+
+// track records a request for q, whose result was cached at cachedAt, so the
+// refresher can renew it before it expires. It does nothing unless refresh-ahead is enabled.
+func (s *StockService) track(q Query, cachedAt time.Time) {
+	if s.config.RefreshAheadWindow <= 0 {
+		return
+	}
+
+	s.trackedMu.Lock()
+	defer s.trackedMu.Unlock()
+
+	if s.tracked == nil {
+		s.tracked = make(map[string]*trackedQuery)
+	}
+	s.tracked[q.cacheKey()] = &trackedQuery{
+		query:         q,
+		lastRequested: time.Now(),
+		expiresAt:     cachedAt.Add(s.config.CacheTTL),
+	}
+}
+
+// dueForRefresh returns the tracked queries expiring within the refresh window and
+// forgets those not requested within the last CacheTTL, which are left to expire
+func (s *StockService) dueForRefresh(now time.Time) []Query {
+	s.trackedMu.Lock()
+	defer s.trackedMu.Unlock()
+
+	var due []Query
+	for key, tracked := range s.tracked {
+		if now.Sub(tracked.lastRequested) > s.config.CacheTTL {
+			delete(s.tracked, key)
+			continue
+		}
+		if now.After(tracked.expiresAt.Add(-s.config.RefreshAheadWindow)) {
+			due = append(due, tracked.query)
+		}
+	}
+	return due
+}
+
+// RefreshDue re-fetches every recently requested query whose cached result expires
+// within the refresh window, replacing the cached result. Refreshes run one at a
+// time to stay within the upstream rate limit and share in-flight fetches with requests.
+func (s *StockService) RefreshDue(ctx context.Context) {
+	for _, q := range s.dueForRefresh(time.Now()) {
+		if ctx.Err() != nil {
+			return
+		}
+
+		key := q.cacheKey()
+		// Requests may be waiting on the same fetch, so it is not cut short by shutdown
+		result, err, _ := s.group.Do(key, func() (interface{}, error) {
+			return s.fetchAndCache(context.WithoutCancel(ctx), q, key, true)
+		})
+		if err != nil {
+			s.logger.WarnContext(ctx, "error refreshing stock data", "symbol", q.Symbol, "days", q.Days, "error", err)
+			continue
+		}
+
+		s.trackedMu.Lock()
+		if tracked, ok := s.tracked[key]; ok {
+			tracked.expiresAt = result.(*models.StockData).CachedAt.Add(s.config.CacheTTL)
+		}
+		s.trackedMu.Unlock()
+		s.logger.DebugContext(ctx, "refreshed stock data", "symbol", q.Symbol, "days", q.Days)
+	}
+}
+
+// StartRefresher launches a background goroutine that calls RefreshDue twice per
+// refresh window, so entries are renewed before they expire. Calling it while a
+// refresher is already running, or with refresh-ahead disabled, has no effect.
+func (s *StockService) StartRefresher() {
+	if s.config.RefreshAheadWindow <= 0 {
+		return
+	}
+
+	s.refresherMu.Lock()
+	defer s.refresherMu.Unlock()
+
+	if s.stopRefresher != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopRefresher = cancel
+	s.refresherWG.Add(1)
+	go s.runRefresher(ctx, s.config.RefreshAheadWindow/2)
+}
+
+// StopRefresher halts the refresher goroutine and waits for it to exit, which
+// includes finishing a refresh in progress. It is safe to call multiple times or when no refresher is running.
+func (s *StockService) StopRefresher() {
+	s.refresherMu.Lock()
+	if s.stopRefresher != nil {
+		s.stopRefresher()
+		s.stopRefresher = nil
+	}
+	s.refresherMu.Unlock()
+
+	s.refresherWG.Wait()
+}
+
+// runRefresher periodically refreshes due entries until ctx is cancelled
+func (s *StockService) runRefresher(ctx context.Context, interval time.Duration) {
+	defer s.refresherWG.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.RefreshDue(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
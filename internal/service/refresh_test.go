@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/cache"
+	"github.com/saedabdu/stockticker/internal/client"
+	"github.com/saedabdu/stockticker/internal/config"
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+func TestRefreshDue(t *testing.T) {
+	provider := &stubProvider{
+		response: &models.AlphaVantageResponse{
+			TimeSeries: map[string]models.DailyPrice{
+				"2023-01-03": {Open: "150.10", High: "150.10", Low: "150.10", Close: "150.10", Volume: "1000"},
+			},
+		},
+	}
+	cfg := &config.Config{CacheConfig: config.CacheConfig{CacheTTL: time.Minute, RefreshAheadWindow: 50 * time.Second}}
+	service := New(cfg, provider, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	hot := Query{Symbol: "AAPL", Days: 7, Interval: client.IntervalDaily}
+	cold := Query{Symbol: "MSFT", Days: 7, Interval: client.IntervalDaily}
+
+	for _, q := range []Query{hot, cold} {
+		if _, err := service.GetStockData(context.Background(), q); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// Both entries expire within the refresh window, but MSFT has not been
+	// requested within the cache TTL
+	for _, q := range []Query{hot, cold} {
+		service.tracked[q.cacheKey()].expiresAt = time.Now().Add(10 * time.Second)
+	}
+	service.tracked[cold.cacheKey()].lastRequested = time.Now().Add(-2 * time.Minute)
+
+	service.RefreshDue(context.Background())
+
+	if provider.calls != 3 {
+		t.Fatalf("expected 3 provider calls, got %d", provider.calls)
+	}
+	if _, ok := service.tracked[cold.cacheKey()]; ok {
+		t.Error("expected cold query to no longer be tracked")
+	}
+	if expiresAt := service.tracked[hot.cacheKey()].expiresAt; time.Until(expiresAt) < 55*time.Second {
+		t.Errorf("expected refreshed expiry about a minute away, got %s", time.Until(expiresAt))
+	}
+}
+
+func TestRefresherDisabled(t *testing.T) {
+	provider := &stubProvider{
+		response: &models.AlphaVantageResponse{
+			TimeSeries: map[string]models.DailyPrice{
+				"2023-01-03": {Open: "150.10", High: "150.10", Low: "150.10", Close: "150.10", Volume: "1000"},
+			},
+		},
+	}
+	service := New(&config.Config{CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}, provider, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	service.StartRefresher()
+	defer service.StopRefresher()
+
+	if _, err := service.GetStockData(context.Background(), Query{Symbol: "AAPL", Days: 7, Interval: client.IntervalDaily}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(service.tracked) != 0 {
+		t.Errorf("expected no tracked queries, got %d", len(service.tracked))
+	}
+}
+
+func TestStartStopRefresher(t *testing.T) {
+	service := New(&config.Config{CacheConfig: config.CacheConfig{CacheTTL: time.Minute, RefreshAheadWindow: time.Second}}, &stubProvider{}, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	service.StartRefresher()
+	service.StartRefresher()
+
+	done := make(chan struct{})
+	go func() {
+		service.StopRefresher()
+		service.StopRefresher()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("StopRefresher did not return")
+	}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/saedabdu/stockticker/internal/client"
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+// ErrSearchUnsupported is returned when a symbol search is requested from a provider without one
+var ErrSearchUnsupported = errors.New("symbol search is not supported by the configured provider")
+
+// SearchSymbols returns the symbols best matching keywords. Results are cached
+// like stock data since every search counts against the provider's rate limit.
+func (s *StockService) SearchSymbols(ctx context.Context, keywords string) ([]models.SymbolMatch, error) {
+	cacheKey := fmt.Sprintf("search:%s", strings.ToLower(keywords))
+	if value, found := s.cache.Get(cacheKey); found {
+		if matches, ok := value.([]models.SymbolMatch); ok {
+			return matches, nil
+		}
+	}
+
+	searcher, ok := s.client.(client.SymbolSearcher)
+	if !ok {
+		return nil, ErrSearchUnsupported
+	}
+
+	matches, err := searcher.SearchSymbols(ctx, keywords)
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.Set(cacheKey, matches, s.config.CacheTTL)
+	return matches, nil
+}
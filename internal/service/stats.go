@@ -0,0 +1,129 @@
+package service
+
+import (
+	"math"
+	"sort"
+)
+
+// median returns the median of values, which must not be empty
+func median(values []float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// minMax returns the smallest and largest of values, which must not be empty
+func minMax(values []float64) (float64, float64) {
+	lo, hi := values[0], values[0]
+	for _, v := range values[1:] {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	return lo, hi
+}
+
+// stdDev returns the population standard deviation of values around mean.
+// A single value has a standard deviation of zero.
+func stdDev(values []float64, mean float64) float64 {
+	var sumSquares float64
+	for _, v := range values {
+		diff := v - mean
+		sumSquares += diff * diff
+	}
+	return math.Sqrt(sumSquares / float64(len(values)))
+}
+
+// pearson returns the Pearson correlation coefficient of the paired values x
+// and y, which must have the same length. The second result is false when there
+// are fewer than two pairs or either series is constant, leaving it undefined.
+func pearson(x, y []float64) (float64, bool) {
+	n := len(x)
+	if n < 2 {
+		return 0, false
+	}
+
+	var sumX, sumY float64
+	for i := range x {
+		sumX += x[i]
+		sumY += y[i]
+	}
+	meanX, meanY := sumX/float64(n), sumY/float64(n)
+
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(varX*varY), true
+}
+
+// percentChange returns the change from oldest to latest as a percentage of oldest.
+// It returns zero when oldest is zero, including the single data point case where
+// oldest and latest are the same entry.
+func percentChange(oldest, latest float64) float64 {
+	if oldest == 0 {
+		return 0
+	}
+	return (latest - oldest) / oldest * 100
+}
+
+// simpleMovingAverage returns the mean of each run of window consecutive values.
+// The result has len(values)-window+1 entries, the first averaging values[0:window],
+// and is empty when there are fewer than window values.
+func simpleMovingAverage(values []float64, window int) []float64 {
+	if window <= 0 || len(values) < window {
+		return nil
+	}
+
+	averages := make([]float64, 0, len(values)-window+1)
+	var sum float64
+	for i, v := range values {
+		sum += v
+		if i >= window {
+			sum -= values[i-window]
+		}
+		if i >= window-1 {
+			averages = append(averages, sum/float64(window))
+		}
+	}
+	return averages
+}
+
+// exponentialMovingAverage returns the exponential moving average of values over
+// window with the smoothing factor 2/(window+1). The first entry is seeded with the
+// mean of values[0:window], so like simpleMovingAverage the result has
+// len(values)-window+1 entries and is empty when there are fewer than window values.
+func exponentialMovingAverage(values []float64, window int) []float64 {
+	if window <= 0 || len(values) < window {
+		return nil
+	}
+
+	alpha := 2 / float64(window+1)
+	averages := make([]float64, 0, len(values)-window+1)
+	var sum float64
+	for _, v := range values[:window] {
+		sum += v
+	}
+	ema := sum / float64(window)
+	averages = append(averages, ema)
+	for _, v := range values[window:] {
+		ema = alpha*v + (1-alpha)*ema
+		averages = append(averages, ema)
+	}
+	return averages
+}
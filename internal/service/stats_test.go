@@ -0,0 +1,339 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+func TestSimpleMovingAverage(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []float64
+		window   int
+		expected []float64
+	}{
+		{
+			name:     "window of three",
+			values:   []float64{1, 2, 3, 4, 5},
+			window:   3,
+			expected: []float64{2, 3, 4},
+		},
+		{
+			name:     "window of one returns the values",
+			values:   []float64{1, 2, 3},
+			window:   1,
+			expected: []float64{1, 2, 3},
+		},
+		{
+			name:     "window equal to length",
+			values:   []float64{2, 4, 6},
+			window:   3,
+			expected: []float64{4},
+		},
+		{
+			name:     "window longer than values",
+			values:   []float64{1, 2},
+			window:   3,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := simpleMovingAverage(tt.values, tt.window)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if !almostEqual(got[i], tt.expected[i]) {
+					t.Errorf("expected %v, got %v", tt.expected, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestMovingAverage(t *testing.T) {
+	// Newest first, as returned by GetStockData
+	prices := []models.StockPrice{
+		{Date: "2023-01-06", Close: 14},
+		{Date: "2023-01-05", Close: 12},
+		{Date: "2023-01-04", Close: 10},
+		{Date: "2023-01-03", Close: 8},
+	}
+
+	got := MovingAverage(prices, 2, models.PriceFieldClose)
+
+	expected := []models.SeriesPoint{
+		{Date: "2023-01-06", Value: 13},
+		{Date: "2023-01-05", Value: 11},
+		{Date: "2023-01-04", Value: 9},
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	for i := range got {
+		if got[i].Date != expected[i].Date || !almostEqual(got[i].Value, expected[i].Value) {
+			t.Errorf("expected %v, got %v", expected, got)
+			break
+		}
+	}
+}
+
+func TestExponentialMovingAverage(t *testing.T) {
+	// Newest first, as returned by GetStockData
+	prices := []models.StockPrice{
+		{Date: "2023-01-09", Close: 20},
+		{Date: "2023-01-06", Close: 13},
+		{Date: "2023-01-05", Close: 12},
+		{Date: "2023-01-04", Close: 11},
+		{Date: "2023-01-03", Close: 10},
+	}
+
+	tests := []struct {
+		name     string
+		window   int
+		expected []models.SeriesPoint
+	}{
+		{
+			// alpha = 2/(3+1) = 0.5, seeded with (10+11+12)/3 = 11
+			name:   "window of 3",
+			window: 3,
+			expected: []models.SeriesPoint{
+				{Date: "2023-01-09", Value: 16}, // 0.5*20 + 0.5*12
+				{Date: "2023-01-06", Value: 12}, // 0.5*13 + 0.5*11
+				{Date: "2023-01-05", Value: 11},
+			},
+		},
+		{
+			// alpha = 2/(4+1) = 0.4, seeded with (10+11+12+13)/4 = 11.5
+			name:   "window of 4",
+			window: 4,
+			expected: []models.SeriesPoint{
+				{Date: "2023-01-09", Value: 14.9}, // 0.4*20 + 0.6*11.5
+				{Date: "2023-01-06", Value: 11.5},
+			},
+		},
+		{
+			name:     "window longer than the prices",
+			window:   6,
+			expected: []models.SeriesPoint{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExponentialMovingAverage(prices, tt.window, models.PriceFieldClose)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i].Date != tt.expected[i].Date || !almostEqual(got[i].Value, tt.expected[i].Value) {
+					t.Errorf("expected %v, got %v", tt.expected, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestReturns(t *testing.T) {
+	// Newest first, as returned by GetStockData
+	prices := []models.StockPrice{
+		{Date: "2023-01-06", Close: 99, Open: 10},
+		{Date: "2023-01-05", Close: 110, Open: 0},
+		{Date: "2023-01-04", Close: 100, Open: 5},
+		{Date: "2023-01-03", Close: 80, Open: 4},
+	}
+
+	tests := []struct {
+		name     string
+		field    models.PriceField
+		expected []models.SeriesPoint
+	}{
+		{
+			name:  "closes",
+			field: models.PriceFieldClose,
+			expected: []models.SeriesPoint{
+				{Date: "2023-01-06", Value: -10},
+				{Date: "2023-01-05", Value: 10},
+				{Date: "2023-01-04", Value: 25},
+			},
+		},
+		{
+			name:  "zero prior is skipped",
+			field: models.PriceFieldOpen,
+			expected: []models.SeriesPoint{
+				{Date: "2023-01-05", Value: -100},
+				{Date: "2023-01-04", Value: 25},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Returns(prices, tt.field)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i].Date != tt.expected[i].Date || !almostEqual(got[i].Value, tt.expected[i].Value) {
+					t.Errorf("expected %v, got %v", tt.expected, got)
+					break
+				}
+			}
+		})
+	}
+
+	if got := Returns(prices[:1], models.PriceFieldClose); len(got) != 0 {
+		t.Errorf("expected no returns for a single price, got %v", got)
+	}
+}
+
+func TestVWAP(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []models.StockPrice
+		want   float64
+		wantOK bool
+	}{
+		{
+			name:   "weighted by volume",
+			prices: []models.StockPrice{{Close: 10, Volume: 100}, {Close: 20, Volume: 300}},
+			want:   17.5,
+			wantOK: true,
+		},
+		{
+			name:   "zero volume",
+			prices: []models.StockPrice{{Close: 10}, {Close: 20}},
+			wantOK: false,
+		},
+		{
+			name:   "no prices",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := VWAP(tt.prices, models.PriceFieldClose)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("expected %v, %v, got %v, %v", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+}
+
+func TestDailyChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []models.StockPrice
+		want   float64
+		wantOK bool
+	}{
+		{
+			name:   "newest against previous close",
+			prices: []models.StockPrice{{Close: 110}, {Close: 100}, {Close: 50}},
+			want:   10,
+			wantOK: true,
+		},
+		{
+			name:   "single price",
+			prices: []models.StockPrice{{Close: 110}},
+			wantOK: false,
+		},
+		{
+			name:   "zero previous close",
+			prices: []models.StockPrice{{Close: 110}, {Close: 0}},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := DailyChange(tt.prices)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("expected %v, %v, got %v, %v", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+}
+
+func TestExtremes(t *testing.T) {
+	prices := []models.StockPrice{
+		{Date: "2023-01-06", Close: 90},
+		{Date: "2023-01-05", Close: 120},
+		{Date: "2023-01-04", Close: 90},
+		{Date: "2023-01-03", Close: 120},
+		{Date: "2023-01-02", Close: 100},
+	}
+
+	high, low, ok := Extremes(prices)
+	if !ok {
+		t.Fatal("expected extremes for a non-empty series")
+	}
+	// Ties resolve to the earliest date
+	if high.Date != "2023-01-03" || high.Close != 120 {
+		t.Errorf("expected high of 120 on 2023-01-03, got %+v", high)
+	}
+	if low.Date != "2023-01-04" || low.Close != 90 {
+		t.Errorf("expected low of 90 on 2023-01-04, got %+v", low)
+	}
+
+	if _, _, ok := Extremes(nil); ok {
+		t.Error("expected no extremes for an empty series")
+	}
+}
+
+func TestCorrelation(t *testing.T) {
+	a := []models.StockPrice{
+		{Date: "2023-01-06", Close: 4},
+		{Date: "2023-01-05", Close: 3},
+		{Date: "2023-01-04", Close: 2},
+		{Date: "2023-01-03", Close: 1},
+	}
+
+	tests := []struct {
+		name       string
+		b          []models.StockPrice
+		want       float64
+		wantPoints int
+		wantOK     bool
+	}{
+		{
+			name:       "moving together",
+			b:          []models.StockPrice{{Date: "2023-01-06", Close: 40}, {Date: "2023-01-05", Close: 30}, {Date: "2023-01-04", Close: 20}, {Date: "2023-01-03", Close: 10}},
+			want:       1,
+			wantPoints: 4,
+			wantOK:     true,
+		},
+		{
+			name:       "opposite moves on aligned dates only",
+			b:          []models.StockPrice{{Date: "2023-01-07", Close: 99}, {Date: "2023-01-06", Close: 1}, {Date: "2023-01-04", Close: 3}},
+			want:       -1,
+			wantPoints: 2,
+			wantOK:     true,
+		},
+		{
+			name:       "single aligned date",
+			b:          []models.StockPrice{{Date: "2023-01-06", Close: 1}, {Date: "2023-01-02", Close: 3}},
+			wantPoints: 1,
+		},
+		{
+			name:       "constant series",
+			b:          []models.StockPrice{{Date: "2023-01-06", Close: 5}, {Date: "2023-01-05", Close: 5}},
+			wantPoints: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, points, ok := Correlation(a, tt.b)
+			if ok != tt.wantOK || points != tt.wantPoints || !almostEqual(got, tt.want) {
+				t.Errorf("expected %v over %d points (%v), got %v over %d points (%v)", tt.want, tt.wantPoints, tt.wantOK, got, points, ok)
+			}
+		})
+	}
+}
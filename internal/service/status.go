@@ -0,0 +1,53 @@
+package service
+
+import "time"
+
+// FetchStatus summarizes the upstream fetches made for a symbol
+type FetchStatus struct {
+	// LastSuccess is when data for the symbol was last fetched successfully; zero if never
+	LastSuccess time.Time
+	// LastError is the most recent failure, kept after later successes; empty if none
+	LastError   string
+	LastErrorAt time.Time
+	// ErrorCount is the number of failed fetches since startup
+	ErrorCount int
+}
+
+// recordFetch records the outcome of an upstream fetch for symbol
+func (s *StockService) recordFetch(symbol string, err error) {
+	if err == nil {
+		s.recordUpstreamSuccess()
+	}
+
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+
+	if s.status == nil {
+		s.status = make(map[string]*FetchStatus)
+	}
+	status, ok := s.status[symbol]
+	if !ok {
+		status = &FetchStatus{}
+		s.status[symbol] = status
+	}
+
+	if err != nil {
+		status.LastError = err.Error()
+		status.LastErrorAt = time.Now()
+		status.ErrorCount++
+		return
+	}
+	status.LastSuccess = time.Now()
+}
+
+// FetchStatuses returns a snapshot of the fetch status of every symbol fetched since startup
+func (s *StockService) FetchStatuses() map[string]FetchStatus {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+
+	statuses := make(map[string]FetchStatus, len(s.status))
+	for symbol, status := range s.status {
+		statuses[symbol] = *status
+	}
+	return statuses
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/saedabdu/stockticker/internal/cache"
+	"github.com/saedabdu/stockticker/internal/client"
+	"github.com/saedabdu/stockticker/internal/config"
+	"github.com/saedabdu/stockticker/pkg/models"
+)
+
+func TestFetchStatuses(t *testing.T) {
+	provider := &stubProvider{
+		response: &models.AlphaVantageResponse{
+			TimeSeries: map[string]models.DailyPrice{
+				"2023-01-03": {Open: "1", High: "1", Low: "1", Close: "1", Volume: "1"},
+			},
+		},
+	}
+	service := New(&config.Config{CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}, provider, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if _, err := service.GetStockData(context.Background(), Query{Symbol: "AAPL", Days: 1, Interval: client.IntervalDaily}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	provider.response, provider.err = nil, client.ErrUpstreamUnavailable
+	for days := 2; days <= 3; days++ {
+		if _, err := service.GetStockData(context.Background(), Query{Symbol: "MSFT", Days: days, Interval: client.IntervalDaily}); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+
+	statuses := service.FetchStatuses()
+	if len(statuses) != 2 {
+		t.Fatalf("expected 2 symbols, got %v", statuses)
+	}
+	if aapl := statuses["AAPL"]; aapl.LastSuccess.IsZero() || aapl.LastError != "" || aapl.ErrorCount != 0 {
+		t.Errorf("expected a successful fetch for AAPL, got %+v", aapl)
+	}
+	if msft := statuses["MSFT"]; !msft.LastSuccess.IsZero() || msft.LastError != client.ErrUpstreamUnavailable.Error() || msft.LastErrorAt.IsZero() || msft.ErrorCount != 2 {
+		t.Errorf("expected 2 failed fetches for MSFT, got %+v", msft)
+	}
+}
@@ -1,109 +1,536 @@
 package service
 
 import (
+	"context"
+	"encoding/gob"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
 	"sort"
 	"strconv"
+	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"golang.org/x/sync/singleflight"
+
 	"github.com/saedabdu/stockticker/internal/cache"
 	"github.com/saedabdu/stockticker/internal/client"
 	"github.com/saedabdu/stockticker/internal/config"
+	"github.com/saedabdu/stockticker/internal/metrics"
 	"github.com/saedabdu/stockticker/pkg/models"
 )
 
-const (
-	cacheDuration = 15 * time.Minute
+var (
+	// ErrAdjustedUnsupported is returned when adjusted closes are requested from a provider without them
+	ErrAdjustedUnsupported = errors.New("adjusted closes are not supported by the configured provider")
+	// ErrSymbolNotAllowed is returned for a symbol missing from ALLOWED_SYMBOLS
+	ErrSymbolNotAllowed = errors.New("symbol is not allowed on this server")
 )
 
+// tracer creates the service's spans; it is a no-op unless tracing is configured
+var tracer = otel.Tracer("github.com/saedabdu/stockticker/internal/service")
+
+func init() {
+	// Register the cached value types so the cache can be persisted to disk or Redis
+	gob.Register(&models.StockData{})
+	gob.Register([]models.SymbolMatch{})
+	gob.Register(&models.Quote{})
+}
+
 // StockService handles stock data retrieval and processing
 type StockService struct {
-	client *client.AlphaVantage
-	cache  *cache.Cache
+	client client.StockProvider
+	cache  cache.Store
 	config *config.Config
+	logger *slog.Logger
+	group  singleflight.Group
+
+	// refresh-ahead state, see refresh.go
+	trackedMu     sync.Mutex
+	tracked       map[string]*trackedQuery
+	refresherMu   sync.Mutex
+	stopRefresher context.CancelFunc
+	refresherWG   sync.WaitGroup
+
+	// upstream health state, see health.go
+	healthMu            sync.Mutex
+	lastUpstreamSuccess time.Time
+	lastUpstreamCheck   time.Time
+	lastUpstreamErr     error
+
+	// per-symbol fetch status, see status.go
+	statusMu sync.Mutex
+	status   map[string]*FetchStatus
+
+	// per-symbol fetch locks, see lock.go
+	symbolLocksMu sync.Mutex
+	symbolLocks   map[string]*symbolLock
 }
 
 // New creates a new StockService
-func New(cfg *config.Config, client *client.AlphaVantage, cache *cache.Cache) *StockService {
+func New(cfg *config.Config, client client.StockProvider, cache cache.Store, logger *slog.Logger) *StockService {
 	return &StockService{
 		client: client,
 		cache:  cache,
 		config: cfg,
+		logger: logger,
+	}
+}
+
+// dateLayout is the format of the date portion of time series keys
+const dateLayout = "2006-01-02"
+
+// Query describes which stock data to retrieve
+type Query struct {
+	Symbol   string
+	Days     int
+	Interval client.Interval
+
+	// From and To select an inclusive date range instead of the latest Days entries.
+	// A zero value leaves that end of the range open.
+	From time.Time
+	To   time.Time
+
+	// Strict fails the query on a malformed entry instead of skipping it
+	Strict bool
+	// Adjusted uses closes adjusted for splits and dividends; it requires the daily interval
+	Adjusted bool
+	// PriceField selects the price the statistics are computed from; empty means the close
+	PriceField models.PriceField
+}
+
+// hasDateRange reports whether the query selects a date range
+func (q Query) hasDateRange() bool {
+	return !q.From.IsZero() || !q.To.IsZero()
+}
+
+// upstreamDays returns how many days of history must be requested from the provider
+func (q Query) upstreamDays() int {
+	if q.From.IsZero() {
+		return q.Days
+	}
+	return int(time.Since(q.From).Hours()/24) + 1
+}
+
+// cacheKey returns the key under which the query's result is cached. Days is
+// left out when From is set, since it then affects neither the upstream
+// request nor the result.
+func (q Query) cacheKey() string {
+	days := q.Days
+	if !q.From.IsZero() {
+		days = 0
 	}
+	return fmt.Sprintf("%s:%d:%s:%s:%s:%t:%t:%s", config.NormalizeSymbol(q.Symbol), days, q.Interval, formatDate(q.From), formatDate(q.To), q.Strict, q.Adjusted, q.PriceField)
 }
 
-// GetStockData retrieves stock data either from cache or the API
-func (s *StockService) GetStockData() (*models.StockData, error) {
-	cacheKey := s.config.Symbol
+// formatDate formats t as a date, or returns an empty string for the zero time
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(dateLayout)
+}
 
-	// Try to get data from cache first
-	if cachedData, found := s.cache.Get(cacheKey); found {
-		return cachedData.(*models.StockData), nil
+// GetStockData retrieves stock data for the given query either from cache or the API
+func (s *StockService) GetStockData(ctx context.Context, q Query) (*models.StockData, error) {
+	q.Symbol = config.NormalizeSymbol(q.Symbol)
+
+	ctx, span := tracer.Start(ctx, "StockService.GetStockData")
+	defer span.End()
+	span.SetAttributes(attribute.String("symbol", q.Symbol), attribute.Int("days", q.Days))
+
+	if err := s.checkAllowed(q.Symbol); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	cacheKey := q.cacheKey()
+
+	// Try to get data from cache first. With stale-if-error, entries outlive
+	// CacheTTL and an expired one is only kept as a fallback for a failed fetch.
+	cachedData, found := s.getCachedStockData(ctx, cacheKey)
+	var fallback *models.StockData
+	if found && s.config.StaleIfError > 0 && time.Since(cachedData.CachedAt) > s.config.CacheTTL {
+		fallback, found = cachedData, false
+	}
+	span.SetAttributes(attribute.Bool("cache_hit", found))
+	if found {
+		metrics.CacheHits.Inc()
+		s.track(q, cachedData.CachedAt)
+		s.logger.DebugContext(ctx, "stock data retrieved",
+			"symbol", q.Symbol, "days", q.Days, "cache_hit", true,
+			"duration_ms", time.Since(start).Milliseconds())
+		return s.checkFreshness(ctx, cachedData)
+	}
+
+	metrics.CacheMisses.Inc()
+
+	// Coalesce concurrent identical requests into a single upstream fetch. The fetch
+	// runs without the caller's cancellation so one client disconnecting does not
+	// fail the others waiting on the same result.
+	resultCh := s.group.DoChan(cacheKey, func() (interface{}, error) {
+		return s.fetchAndCache(context.WithoutCancel(ctx), q, cacheKey, false)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-resultCh:
+		if result.Err != nil {
+			span.RecordError(result.Err)
+			span.SetStatus(codes.Error, result.Err.Error())
+			s.logger.WarnContext(ctx, "upstream fetch failed",
+				"symbol", q.Symbol, "days", q.Days, "cache_hit", false, "shared", result.Shared,
+				"duration_ms", time.Since(start).Milliseconds(), "error", result.Err)
+			if fallback != nil {
+				s.logger.WarnContext(ctx, "serving stale stock data",
+					"symbol", q.Symbol, "days", q.Days, "age", time.Since(fallback.CachedAt).Round(time.Second))
+				stale := *fallback
+				stale.ServedStale = true
+				return s.checkFreshness(ctx, &stale)
+			}
+			return nil, result.Err
+		}
+
+		s.logger.InfoContext(ctx, "stock data retrieved",
+			"symbol", q.Symbol, "days", q.Days, "cache_hit", false, "shared", result.Shared,
+			"duration_ms", time.Since(start).Milliseconds())
+		stockData := result.Val.(*models.StockData)
+		s.track(q, stockData.CachedAt)
+		return s.checkFreshness(ctx, stockData)
+	}
+}
+
+// checkAllowed rejects symbols missing from ALLOWED_SYMBOLS before they reach
+// the cache or the provider
+func (s *StockService) checkAllowed(symbol string) error {
+	if !s.config.SymbolAllowed(symbol) {
+		return fmt.Errorf("%w: %s", ErrSymbolNotAllowed, symbol)
+	}
+	return nil
+}
+
+// fetchAndCache retrieves stock data from the API, processes it and caches the
+// result. Unless refresh is set, a fresh result cached by another fetch while
+// this one waited for the symbol lock is returned instead.
+func (s *StockService) fetchAndCache(ctx context.Context, q Query, cacheKey string, refresh bool) (*models.StockData, error) {
+	// Held until the result is cached so fetches of other windows of the symbol wait
+	unlock := s.lockSymbol(q.Symbol)
+	defer unlock()
+	if !refresh {
+		if cachedData, found := s.getCachedStockData(ctx, cacheKey); found && time.Since(cachedData.CachedAt) <= s.config.CacheTTL {
+			return cachedData, nil
+		}
 	}
 
 	// Get data from the API - pass the number of days to ensure we get enough data
-	apiResponse, err := s.client.GetStockData(s.config.Symbol, s.config.NDays)
+	apiResponse, err := s.fetch(ctx, q)
+	s.recordFetch(q.Symbol, err)
 	if err != nil {
 		return nil, err
 	}
 
 	// Process the API response
-	stockData, err := s.processAPIResponse(apiResponse)
+	stockData, err := s.processAPIResponse(ctx, q, apiResponse)
 	if err != nil {
 		return nil, err
 	}
 
-	// Cache the response
-	s.cache.Set(cacheKey, stockData, cacheDuration)
+	// Cache the response, keeping it past CacheTTL as a fallback when stale-if-error is enabled
+	stockData.CachedAt = time.Now()
+	s.cache.Set(cacheKey, stockData, s.config.CacheTTL+s.config.StaleIfError)
 
 	return stockData, nil
 }
 
-// processAPIResponse converts the API response to our model and calculates the average
-func (s *StockService) processAPIResponse(apiResponse *models.AlphaVantageResponse) (*models.StockData, error) {
+// fetch requests the query's time series from the provider
+func (s *StockService) fetch(ctx context.Context, q Query) (*models.AlphaVantageResponse, error) {
+	if !q.Adjusted {
+		return s.client.GetStockData(ctx, q.Symbol, q.upstreamDays(), q.Interval)
+	}
+
+	provider, ok := s.client.(client.AdjustedStockProvider)
+	if !ok {
+		return nil, ErrAdjustedUnsupported
+	}
+	return provider.GetAdjustedStockData(ctx, q.Symbol, q.upstreamDays())
+}
+
+// getCachedStockData returns the stock data cached under key. A value of any
+// other type is treated as a miss rather than trusted.
+func (s *StockService) getCachedStockData(ctx context.Context, key string) (*models.StockData, bool) {
+	_, span := tracer.Start(ctx, "cache.Get")
+	value, found := s.cache.Get(key)
+	span.SetAttributes(attribute.Bool("cache_hit", found))
+	span.End()
+
+	if !found {
+		return nil, false
+	}
+
+	stockData, ok := value.(*models.StockData)
+	if !ok {
+		s.logger.WarnContext(ctx, "unexpected cached value type", "key", key, "type", fmt.Sprintf("%T", value))
+		return nil, false
+	}
+
+	return stockData, true
+}
+
+// InvalidateSymbol removes every cached result for symbol, whatever its window or
+// interval, and its cached quote, so the next request for it is fetched from the provider
+func (s *StockService) InvalidateSymbol(ctx context.Context, symbol string) {
+	symbol = config.NormalizeSymbol(symbol)
+	// Stock cache keys start with the symbol followed by a colon, see Query.cacheKey
+	s.cache.DeletePrefix(symbol + ":")
+	s.cache.Delete(quoteCacheKey(symbol))
+	s.logger.InfoContext(ctx, "cache invalidated", "symbol", symbol)
+}
+
+// CacheStats returns the effectiveness counters of the underlying cache
+func (s *StockService) CacheStats() cache.Stats {
+	return s.cache.Stats()
+}
+
+// SymbolResult holds the outcome of fetching a single symbol in a multi-symbol request
+type SymbolResult struct {
+	Symbol string
+	Data   *models.StockData
+	Err    error
+}
+
+// GetMultipleStockData retrieves stock data for several symbols using a pool of
+// Concurrency workers, using q for every setting other than the symbol.
+// Results are returned in the same order as symbols; a failure for one symbol
+// is reported in its result and does not affect the others.
+func (s *StockService) GetMultipleStockData(ctx context.Context, symbols []string, q Query) []SymbolResult {
+	results := make([]SymbolResult, len(symbols))
+	jobs := make(chan int)
+
+	workers := s.config.Concurrency
+	if workers <= 0 {
+		workers = config.DefaultConcurrency
+	}
+	if len(symbols) < workers {
+		workers = len(symbols)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				symbolQuery := q
+				symbolQuery.Symbol = symbols[idx]
+				data, err := s.GetStockData(ctx, symbolQuery)
+				results[idx] = SymbolResult{Symbol: symbols[idx], Data: data, Err: err}
+			}
+		}()
+	}
+
+	// Symbols not yet handed to a worker when ctx is cancelled fail with its error
+dispatch:
+	for idx := range symbols {
+		select {
+		case jobs <- idx:
+		case <-ctx.Done():
+			for i := idx; i < len(symbols); i++ {
+				results[i] = SymbolResult{Symbol: symbols[i], Err: ctx.Err()}
+			}
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// IntervalResult holds the outcome of fetching a single interval in a multi-interval request
+type IntervalResult struct {
+	Interval client.Interval
+	Data     *models.StockData
+	Err      error
+}
+
+// GetMultipleIntervalData retrieves the query's symbol at each of the given
+// intervals concurrently. Each interval is fetched and cached independently, as
+// if requested on its own. Results are returned in the order of intervals.
+func (s *StockService) GetMultipleIntervalData(ctx context.Context, q Query, intervals []client.Interval) []IntervalResult {
+	results := make([]IntervalResult, len(intervals))
+
+	var wg sync.WaitGroup
+	for i, interval := range intervals {
+		wg.Add(1)
+		go func(i int, interval client.Interval) {
+			defer wg.Done()
+			intervalQuery := q
+			intervalQuery.Interval = interval
+			data, err := s.GetStockData(ctx, intervalQuery)
+			results[i] = IntervalResult{Interval: interval, Data: data, Err: err}
+		}(i, interval)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// processAPIResponse converts the API response to our model and calculates the average and summary statistics
+func (s *StockService) processAPIResponse(ctx context.Context, q Query, apiResponse *models.AlphaVantageResponse) (*models.StockData, error) {
 	var prices []models.StockPrice
+	var closes []float64
 	var totalClose float64
 
-	// Extract dates and sort them
+	// Extract dates, filtering by the requested range if any, and sort them
+	from, to := formatDate(q.From), formatDate(q.To)
 	dates := make([]string, 0, len(apiResponse.TimeSeries))
 	for date := range apiResponse.TimeSeries {
+		// Compare only the date portion so intraday timestamps are included
+		day := date
+		if len(day) > len(dateLayout) {
+			day = day[:len(dateLayout)]
+		}
+		if (from != "" && day < from) || (to != "" && day > to) {
+			continue
+		}
 		dates = append(dates, date)
 	}
 
 	// Sort dates in descending order (newest first)
 	sort.Sort(sort.Reverse(sort.StringSlice(dates)))
 
-	// Limit to the requested number of days
-	if len(dates) > s.config.NDays {
-		dates = dates[:s.config.NDays]
-	}
-
-	// Process each date's data
+	// Process each date's data, limited to the requested number of days unless a
+	// date range was given. Malformed entries are skipped unless the query is strict.
+	var firstErr error
 	for _, date := range dates {
-		dailyPrice := apiResponse.TimeSeries[date]
-		closePrice, err := strconv.ParseFloat(dailyPrice.Close, 64)
+		if !q.hasDateRange() && len(prices) == q.Days {
+			break
+		}
+
+		entry := apiResponse.TimeSeries[date]
+		price, err := parseDailyPrice(date, entry)
+		if err == nil && q.Adjusted {
+			price, err = adjustPrice(date, price, entry.AdjustedClose)
+		}
 		if err != nil {
-			return nil, fmt.Errorf("error parsing close price for date %s: %w", date, err)
+			if q.Strict {
+				return nil, err
+			}
+			s.logger.WarnContext(ctx, "skipping malformed price entry", "symbol", q.Symbol, "date", date, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
 		}
 
-		prices = append(prices, models.StockPrice{
-			Date:  date,
-			Close: closePrice,
-		})
+		prices = append(prices, price)
+		closes = append(closes, price.Value(q.PriceField))
 
-		totalClose += closePrice
+		totalClose += price.Value(q.PriceField)
 	}
 
 	if len(prices) == 0 {
-		return nil, fmt.Errorf("no price data available for symbol %s", s.config.Symbol)
+		if firstErr != nil {
+			return nil, fmt.Errorf("no valid price data available for symbol %s: %w", q.Symbol, firstErr)
+		}
+		return nil, fmt.Errorf("no price data available for symbol %s", q.Symbol)
+	}
+
+	// Date range queries return every day in the range rather than a number of days
+	requestedDays := q.Days
+	if q.hasDateRange() {
+		requestedDays = 0
 	}
 
-	// Calculate average
+	// Calculate average and summary statistics
 	average := totalClose / float64(len(prices))
+	minClose, maxClose := minMax(closes)
 
 	return &models.StockData{
-		Symbol:  s.config.Symbol,
+		Symbol:  q.Symbol,
 		Prices:  prices,
-		Average: average,
+		Average: s.roundAverage(average),
+		Median:  median(closes),
+		Min:     minClose,
+		Max:     maxClose,
+		StdDev:  stdDev(closes, average),
+
+		PercentChange: percentChange(closes[len(closes)-1], closes[0]),
+		Currency:      BaseCurrency,
+		LastRefreshed: apiResponse.MetaData.LastRefreshed,
+		TimeZone:      apiResponse.MetaData.TimeZone,
+		RequestedDays: requestedDays,
+		ReturnedDays:  len(prices),
+		PriceField:    q.PriceField,
 	}, nil
 }
+
+// roundAverage rounds an average close to ROUND_AVERAGE decimals, if set
+func (s *StockService) roundAverage(average float64) float64 {
+	if s.config.RoundAverage == 0 {
+		return average
+	}
+	scale := math.Pow10(s.config.RoundAverage)
+	return math.Round(average*scale) / scale
+}
+
+// parseDailyPrice converts an AlphaVantage daily entry into a StockPrice
+func parseDailyPrice(date string, dailyPrice models.DailyPrice) (models.StockPrice, error) {
+	openPrice, err := strconv.ParseFloat(dailyPrice.Open, 64)
+	if err != nil {
+		return models.StockPrice{}, fmt.Errorf("error parsing open price for date %s: %w", date, err)
+	}
+
+	highPrice, err := strconv.ParseFloat(dailyPrice.High, 64)
+	if err != nil {
+		return models.StockPrice{}, fmt.Errorf("error parsing high price for date %s: %w", date, err)
+	}
+
+	lowPrice, err := strconv.ParseFloat(dailyPrice.Low, 64)
+	if err != nil {
+		return models.StockPrice{}, fmt.Errorf("error parsing low price for date %s: %w", date, err)
+	}
+
+	closePrice, err := strconv.ParseFloat(dailyPrice.Close, 64)
+	if err != nil {
+		return models.StockPrice{}, fmt.Errorf("error parsing close price for date %s: %w", date, err)
+	}
+
+	volume, err := strconv.ParseInt(dailyPrice.Volume, 10, 64)
+	if err != nil {
+		return models.StockPrice{}, fmt.Errorf("error parsing volume for date %s: %w", date, err)
+	}
+
+	return models.StockPrice{
+		Date:   date,
+		Open:   openPrice,
+		High:   highPrice,
+		Low:    lowPrice,
+		Close:  closePrice,
+		Volume: volume,
+	}, nil
+}
+
+// adjustPrice replaces the close of price with adjustedClose, scaling the open,
+// high and low by the same factor so the entry stays consistent
+func adjustPrice(date string, price models.StockPrice, adjustedClose string) (models.StockPrice, error) {
+	adjusted, err := strconv.ParseFloat(adjustedClose, 64)
+	if err != nil {
+		return models.StockPrice{}, fmt.Errorf("error parsing adjusted close price for date %s: %w", date, err)
+	}
+	if price.Close == 0 {
+		return models.StockPrice{}, fmt.Errorf("error adjusting prices for date %s: close price is zero", date)
+	}
+
+	factor := adjusted / price.Close
+	price.Open *= factor
+	price.High *= factor
+	price.Low *= factor
+	price.Close = adjusted
+	return price, nil
+}
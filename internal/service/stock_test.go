@@ -1,8 +1,15 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/saedabdu/stockticker/internal/cache"
 	"github.com/saedabdu/stockticker/internal/client"
@@ -15,6 +22,7 @@ func TestProcessAPIResponse(t *testing.T) {
 		name           string
 		apiResponse    *models.AlphaVantageResponse
 		config         *config.Config
+		strict         bool
 		expectedData   *models.StockData
 		expectedError  bool
 		expectedErrMsg string
@@ -23,9 +31,9 @@ func TestProcessAPIResponse(t *testing.T) {
 			name: "successful processing",
 			apiResponse: &models.AlphaVantageResponse{
 				TimeSeries: map[string]models.DailyPrice{
-					"2023-01-03": {Close: "150.10"},
-					"2023-01-02": {Close: "145.50"},
-					"2023-01-01": {Close: "140.20"},
+					"2023-01-03": {Open: "148.00", High: "151.25", Low: "147.80", Close: "150.10", Volume: "1200"},
+					"2023-01-02": {Open: "141.00", High: "146.00", Low: "140.90", Close: "145.50", Volume: "1100"},
+					"2023-01-01": {Open: "139.50", High: "141.10", Low: "138.75", Close: "140.20", Volume: "1000"},
 				},
 			},
 			config: &config.Config{
@@ -35,11 +43,17 @@ func TestProcessAPIResponse(t *testing.T) {
 			expectedData: &models.StockData{
 				Symbol: "AAPL",
 				Prices: []models.StockPrice{
-					{Date: "2023-01-03", Close: 150.10},
-					{Date: "2023-01-02", Close: 145.50},
-					{Date: "2023-01-01", Close: 140.20},
+					{Date: "2023-01-03", Open: 148.00, High: 151.25, Low: 147.80, Close: 150.10, Volume: 1200},
+					{Date: "2023-01-02", Open: 141.00, High: 146.00, Low: 140.90, Close: 145.50, Volume: 1100},
+					{Date: "2023-01-01", Open: 139.50, High: 141.10, Low: 138.75, Close: 140.20, Volume: 1000},
 				},
 				Average: 145.26666666666668, // (150.10 + 145.50 + 140.20) / 3
+				Median:  145.50,
+				Min:     140.20,
+				Max:     150.10,
+				StdDev:  4.045024378445975,
+
+				PercentChange: 7.06134094151213, // (150.10 - 140.20) / 140.20 * 100
 			},
 			expectedError: false,
 		},
@@ -47,11 +61,11 @@ func TestProcessAPIResponse(t *testing.T) {
 			name: "limit days to config",
 			apiResponse: &models.AlphaVantageResponse{
 				TimeSeries: map[string]models.DailyPrice{
-					"2023-01-05": {Close: "160.00"},
-					"2023-01-04": {Close: "155.75"},
-					"2023-01-03": {Close: "150.10"},
-					"2023-01-02": {Close: "145.50"},
-					"2023-01-01": {Close: "140.20"},
+					"2023-01-05": {Open: "160.00", High: "160.00", Low: "160.00", Close: "160.00", Volume: "1000"},
+					"2023-01-04": {Open: "155.75", High: "155.75", Low: "155.75", Close: "155.75", Volume: "1000"},
+					"2023-01-03": {Open: "150.10", High: "150.10", Low: "150.10", Close: "150.10", Volume: "1000"},
+					"2023-01-02": {Open: "145.50", High: "145.50", Low: "145.50", Close: "145.50", Volume: "1000"},
+					"2023-01-01": {Open: "140.20", High: "140.20", Low: "140.20", Close: "140.20", Volume: "1000"},
 				},
 			},
 			config: &config.Config{
@@ -61,11 +75,41 @@ func TestProcessAPIResponse(t *testing.T) {
 			expectedData: &models.StockData{
 				Symbol: "AAPL",
 				Prices: []models.StockPrice{
-					{Date: "2023-01-05", Close: 160.00},
-					{Date: "2023-01-04", Close: 155.75},
-					{Date: "2023-01-03", Close: 150.10},
+					{Date: "2023-01-05", Open: 160.00, High: 160.00, Low: 160.00, Close: 160.00, Volume: 1000},
+					{Date: "2023-01-04", Open: 155.75, High: 155.75, Low: 155.75, Close: 155.75, Volume: 1000},
+					{Date: "2023-01-03", Open: 150.10, High: 150.10, Low: 150.10, Close: 150.10, Volume: 1000},
 				},
 				Average: 155.28333333333333, // (160.00 + 155.75 + 150.10) / 3
+				Median:  155.75,
+				Min:     150.10,
+				Max:     160.00,
+				StdDev:  4.0551065200422185,
+
+				PercentChange: 6.595602931379081, // (160.00 - 150.10) / 150.10 * 100
+			},
+			expectedError: false,
+		},
+		{
+			name: "single data point with more days requested",
+			apiResponse: &models.AlphaVantageResponse{
+				TimeSeries: map[string]models.DailyPrice{
+					"2023-01-03": {Open: "150.10", High: "150.10", Low: "150.10", Close: "150.10", Volume: "1000"},
+				},
+			},
+			config: &config.Config{
+				Symbol: "AAPL",
+				NDays:  7,
+			},
+			expectedData: &models.StockData{
+				Symbol: "AAPL",
+				Prices: []models.StockPrice{
+					{Date: "2023-01-03", Open: 150.10, High: 150.10, Low: 150.10, Close: 150.10, Volume: 1000},
+				},
+				Average: 150.10,
+				Median:  150.10,
+				Min:     150.10,
+				Max:     150.10,
+				StdDev:  0,
 			},
 			expectedError: false,
 		},
@@ -73,17 +117,61 @@ func TestProcessAPIResponse(t *testing.T) {
 			name: "invalid close price",
 			apiResponse: &models.AlphaVantageResponse{
 				TimeSeries: map[string]models.DailyPrice{
-					"2023-01-03": {Close: "invalid"},
-					"2023-01-02": {Close: "145.50"},
+					"2023-01-03": {Open: "150.10", High: "150.10", Low: "150.10", Close: "invalid", Volume: "1000"},
+					"2023-01-02": {Open: "145.50", High: "145.50", Low: "145.50", Close: "145.50", Volume: "1000"},
 				},
 			},
 			config: &config.Config{
 				Symbol: "AAPL",
 				NDays:  3,
 			},
+			strict:         true,
 			expectedError:  true,
 			expectedErrMsg: "error parsing close price for date 2023-01-03",
 		},
+		{
+			name: "skips invalid entries",
+			apiResponse: &models.AlphaVantageResponse{
+				TimeSeries: map[string]models.DailyPrice{
+					"2023-01-03": {Open: "150.10", High: "150.10", Low: "150.10", Close: "invalid", Volume: "1000"},
+					"2023-01-02": {Open: "145.50", High: "145.50", Low: "145.50", Close: "145.50", Volume: "1000"},
+					"2023-01-01": {Open: "140.20", High: "140.20", Low: "140.20", Close: "140.20", Volume: "1000"},
+				},
+			},
+			config: &config.Config{
+				Symbol: "AAPL",
+				NDays:  2,
+			},
+			expectedData: &models.StockData{
+				Symbol: "AAPL",
+				Prices: []models.StockPrice{
+					{Date: "2023-01-02", Open: 145.50, High: 145.50, Low: 145.50, Close: 145.50, Volume: 1000},
+					{Date: "2023-01-01", Open: 140.20, High: 140.20, Low: 140.20, Close: 140.20, Volume: 1000},
+				},
+				Average: 142.85,
+				Median:  142.85,
+				Min:     140.20,
+				Max:     145.50,
+				StdDev:  2.65,
+
+				PercentChange: 3.780313837375187, // (145.50 - 140.20) / 140.20 * 100
+			},
+			expectedError: false,
+		},
+		{
+			name: "invalid volume",
+			apiResponse: &models.AlphaVantageResponse{
+				TimeSeries: map[string]models.DailyPrice{
+					"2023-01-03": {Open: "150.10", High: "150.10", Low: "150.10", Close: "150.10", Volume: "n/a"},
+				},
+			},
+			config: &config.Config{
+				Symbol: "AAPL",
+				NDays:  3,
+			},
+			expectedError:  true,
+			expectedErrMsg: "no valid price data available for symbol AAPL: error parsing volume for date 2023-01-03",
+		},
 		{
 			name: "no price data",
 			apiResponse: &models.AlphaVantageResponse{
@@ -103,12 +191,13 @@ func TestProcessAPIResponse(t *testing.T) {
 			// Create a service instance with the test config
 			service := &StockService{
 				config: tt.config,
-				client: &client.AlphaVantage{}, // Using empty client since we're testing processAPIResponse directly
-				cache:  cache.New(),
+				client: &stubProvider{}, // Using a stub since we're testing processAPIResponse directly
+				cache:  cache.New(0),
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
 			}
 
 			// Call the function under test
-			result, err := service.processAPIResponse(tt.apiResponse)
+			result, err := service.processAPIResponse(context.Background(), Query{Symbol: tt.config.Symbol, Days: tt.config.NDays, Strict: tt.strict}, tt.apiResponse)
 
 			// Verify error cases
 			if tt.expectedError {
@@ -137,11 +226,26 @@ func TestProcessAPIResponse(t *testing.T) {
 				t.Errorf("expected Average %f, got %f", tt.expectedData.Average, result.Average)
 			}
 
+			if !almostEqual(result.Median, tt.expectedData.Median) ||
+				!almostEqual(result.Min, tt.expectedData.Min) ||
+				!almostEqual(result.Max, tt.expectedData.Max) ||
+				!almostEqual(result.StdDev, tt.expectedData.StdDev) ||
+				!almostEqual(result.PercentChange, tt.expectedData.PercentChange) {
+				t.Errorf("expected median/min/max/stddev/change %f/%f/%f/%f/%f, got %f/%f/%f/%f/%f",
+					tt.expectedData.Median, tt.expectedData.Min, tt.expectedData.Max, tt.expectedData.StdDev, tt.expectedData.PercentChange,
+					result.Median, result.Min, result.Max, result.StdDev, result.PercentChange)
+			}
+
 			if len(result.Prices) != len(tt.expectedData.Prices) {
 				t.Errorf("expected %d prices, got %d", len(tt.expectedData.Prices), len(result.Prices))
 				return
 			}
 
+			if result.RequestedDays != tt.config.NDays || result.ReturnedDays != len(tt.expectedData.Prices) {
+				t.Errorf("expected %d requested and %d returned days, got %d and %d",
+					tt.config.NDays, len(tt.expectedData.Prices), result.RequestedDays, result.ReturnedDays)
+			}
+
 			// Verify each price item
 			for i, expectedPrice := range tt.expectedData.Prices {
 				if i >= len(result.Prices) {
@@ -157,12 +261,464 @@ func TestProcessAPIResponse(t *testing.T) {
 				if expectedPrice.Close != actualPrice.Close {
 					t.Errorf("price[%d]: expected Close %f, got %f", i, expectedPrice.Close, actualPrice.Close)
 				}
+
+				if expectedPrice != actualPrice {
+					t.Errorf("price[%d]: expected %+v, got %+v", i, expectedPrice, actualPrice)
+				}
 			}
 		})
 	}
 }
 
+func TestProcessAPIResponseDateRange(t *testing.T) {
+	apiResponse := &models.AlphaVantageResponse{
+		TimeSeries: map[string]models.DailyPrice{
+			"2023-04-03": {Open: "170.00", High: "170.00", Low: "170.00", Close: "170.00", Volume: "1000"},
+			"2023-03-31": {Open: "160.00", High: "160.00", Low: "160.00", Close: "160.00", Volume: "1000"},
+			"2023-02-15": {Open: "150.00", High: "150.00", Low: "150.00", Close: "150.00", Volume: "1000"},
+			"2023-01-03": {Open: "140.00", High: "140.00", Low: "140.00", Close: "140.00", Volume: "1000"},
+			"2022-12-30": {Open: "130.00", High: "130.00", Low: "130.00", Close: "130.00", Volume: "1000"},
+		},
+	}
+	service := &StockService{config: &config.Config{}, client: &stubProvider{}, cache: cache.New(0), logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	query := Query{
+		Symbol: "AAPL",
+		Days:   1, // ignored when a date range is given
+		From:   time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC),
+	}
+
+	result, err := service.processAPIResponse(context.Background(), query, apiResponse)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var dates []string
+	for _, price := range result.Prices {
+		dates = append(dates, price.Date)
+	}
+	if got, want := strings.Join(dates, ","), "2023-03-31,2023-02-15,2023-01-03"; got != want {
+		t.Errorf("expected dates %s, got %s", want, got)
+	}
+	if result.Average != 150 {
+		t.Errorf("expected Average 150, got %f", result.Average)
+	}
+}
+
+func TestProcessAPIResponseRoundsAverage(t *testing.T) {
+	apiResponse := &models.AlphaVantageResponse{
+		TimeSeries: map[string]models.DailyPrice{
+			"2023-01-04": {Open: "1", High: "1", Low: "1", Close: "1", Volume: "1000"},
+			"2023-01-03": {Open: "1", High: "1", Low: "1", Close: "1", Volume: "1000"},
+			"2023-01-02": {Open: "2", High: "2", Low: "2", Close: "2", Volume: "1000"},
+		},
+	}
+	query := Query{Symbol: "AAPL", Days: 3}
+
+	tests := []struct {
+		roundAverage int
+		want         float64
+	}{
+		{roundAverage: 0, want: 4.0 / 3},
+		{roundAverage: 2, want: 1.33},
+	}
+
+	for _, tt := range tests {
+		service := &StockService{config: &config.Config{RoundAverage: tt.roundAverage}, client: &stubProvider{}, cache: cache.New(0), logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+		result, err := service.processAPIResponse(context.Background(), query, apiResponse)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Average != tt.want {
+			t.Errorf("ROUND_AVERAGE=%d: expected Average %v, got %v", tt.roundAverage, tt.want, result.Average)
+		}
+	}
+}
+
+func TestProcessAPIResponsePriceField(t *testing.T) {
+	apiResponse := &models.AlphaVantageResponse{
+		TimeSeries: map[string]models.DailyPrice{
+			"2023-01-04": {Open: "10", High: "30", Low: "5", Close: "20", Volume: "1000"},
+			"2023-01-03": {Open: "12", High: "34", Low: "7", Close: "22", Volume: "1000"},
+		},
+	}
+	service := &StockService{config: &config.Config{}, client: &stubProvider{}, cache: cache.New(0), logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	tests := []struct {
+		field models.PriceField
+		want  float64
+	}{
+		{field: "", want: 21},
+		{field: models.PriceFieldOpen, want: 11},
+		{field: models.PriceFieldHigh, want: 32},
+		{field: models.PriceFieldLow, want: 6},
+	}
+
+	for _, tt := range tests {
+		result, err := service.processAPIResponse(context.Background(), Query{Symbol: "AAPL", Days: 2, PriceField: tt.field}, apiResponse)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Average != tt.want || result.PriceField != tt.field {
+			t.Errorf("price field %q: expected average %v, got %v with field %q", tt.field, tt.want, result.Average, result.PriceField)
+		}
+		// Every price is returned whichever field the statistics use
+		if result.Prices[0].Close != 20 || result.Prices[0].Open != 10 {
+			t.Errorf("price field %q: expected the full prices, got %+v", tt.field, result.Prices[0])
+		}
+	}
+}
+
+func TestGetStockDataAdjusted(t *testing.T) {
+	provider := &adjustedProvider{stubProvider: stubProvider{
+		response: &models.AlphaVantageResponse{
+			TimeSeries: map[string]models.DailyPrice{
+				"2023-01-04": {Open: "100.00", High: "110.00", Low: "90.00", Close: "100.00", AdjustedClose: "50.00", Volume: "1000"},
+				"2023-01-03": {Open: "80.00", High: "80.00", Low: "80.00", Close: "80.00", AdjustedClose: "40.00", Volume: "1000"},
+			},
+		},
+	}}
+	service := New(&config.Config{CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}, provider, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	data, err := service.GetStockData(context.Background(), Query{Symbol: "AAPL", Days: 2, Interval: client.IntervalDaily, Adjusted: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := models.StockPrice{Date: "2023-01-04", Open: 50, High: 55, Low: 45, Close: 50, Volume: 1000}
+	if data.Prices[0] != want {
+		t.Errorf("expected %+v, got %+v", want, data.Prices[0])
+	}
+	if data.Average != 45 {
+		t.Errorf("expected Average 45, got %f", data.Average)
+	}
+	if provider.adjustedCalls != 1 || provider.calls != 0 {
+		t.Errorf("expected only the adjusted series to be fetched, got %d adjusted and %d raw calls", provider.adjustedCalls, provider.calls)
+	}
+
+	// Providers without adjusted closes are rejected
+	unsupported := New(&config.Config{CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}, &stubProvider{}, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	_, err = unsupported.GetStockData(context.Background(), Query{Symbol: "AAPL", Days: 2, Interval: client.IntervalDaily, Adjusted: true})
+	if !errors.Is(err, ErrAdjustedUnsupported) {
+		t.Errorf("expected ErrAdjustedUnsupported, got %v", err)
+	}
+}
+
+// adjustedProvider is a client.AdjustedStockProvider returning a canned response
+type adjustedProvider struct {
+	stubProvider
+	adjustedCalls int
+}
+
+func (p *adjustedProvider) GetAdjustedStockData(ctx context.Context, symbol string, days int) (*models.AlphaVantageResponse, error) {
+	p.adjustedCalls++
+	return p.response, p.err
+}
+
+func TestGetStockDataUsesCache(t *testing.T) {
+	provider := &stubProvider{
+		response: &models.AlphaVantageResponse{
+			TimeSeries: map[string]models.DailyPrice{
+				"2023-01-03": {Open: "150.10", High: "150.10", Low: "150.10", Close: "150.10", Volume: "1000"},
+			},
+		},
+	}
+	service := New(&config.Config{Symbol: "AAPL", NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}, provider, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	query := Query{Symbol: "AAPL", Days: 7, Interval: client.IntervalDaily}
+
+	for i := 0; i < 3; i++ {
+		data, err := service.GetStockData(context.Background(), query)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if data.Symbol != "AAPL" {
+			t.Errorf("expected Symbol AAPL, got %s", data.Symbol)
+		}
+	}
+
+	if provider.calls != 1 {
+		t.Errorf("expected 1 provider call, got %d", provider.calls)
+	}
+}
+
+func TestGetStockDataDateRangeIgnoresDaysInCache(t *testing.T) {
+	provider := &stubProvider{
+		response: &models.AlphaVantageResponse{
+			TimeSeries: map[string]models.DailyPrice{
+				"2023-01-03": {Open: "150.10", High: "150.10", Low: "150.10", Close: "150.10", Volume: "1000"},
+			},
+		},
+	}
+	service := New(&config.Config{NDays: 7, CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}, provider, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	from := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	for _, days := range []int{5, 30} {
+		query := Query{Symbol: "AAPL", Days: days, Interval: client.IntervalDaily, From: from, To: to}
+		if _, err := service.GetStockData(context.Background(), query); err != nil {
+			t.Fatalf("days %d: unexpected error: %v", days, err)
+		}
+	}
+
+	if provider.calls != 1 {
+		t.Errorf("expected 1 provider call for the same date range, got %d", provider.calls)
+	}
+}
+
+func TestGetStockDataServesStaleOnError(t *testing.T) {
+	provider := &stubProvider{
+		response: &models.AlphaVantageResponse{
+			TimeSeries: map[string]models.DailyPrice{
+				"2023-01-03": {Open: "150.10", High: "150.10", Low: "150.10", Close: "150.10", Volume: "1000"},
+			},
+		},
+	}
+	service := New(&config.Config{CacheConfig: config.CacheConfig{CacheTTL: 10 * time.Millisecond, StaleIfError: time.Minute}}, provider, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	query := Query{Symbol: "AAPL", Days: 7, Interval: client.IntervalDaily}
+
+	if _, err := service.GetStockData(context.Background(), query); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Let the entry expire, then fail the refetch
+	time.Sleep(20 * time.Millisecond)
+	provider.response, provider.err = nil, errors.New("upstream unavailable")
+
+	data, err := service.GetStockData(context.Background(), query)
+	if err != nil {
+		t.Fatalf("expected stale data, got error: %v", err)
+	}
+	if !data.ServedStale {
+		t.Error("expected data to be marked as served stale")
+	}
+	if len(data.Prices) != 1 || data.Prices[0].Close != 150.10 {
+		t.Errorf("expected the expired cached prices, got %+v", data.Prices)
+	}
+	if provider.calls != 2 {
+		t.Errorf("expected 2 provider calls, got %d", provider.calls)
+	}
+}
+
+func TestGetStockDataNormalizesSymbol(t *testing.T) {
+	provider := &symbolRecordingProvider{}
+	store := cache.New(0)
+	service := New(&config.Config{CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}, provider, store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	for _, symbol := range []string{"aapl", "AAPL", " AAPL ", "Aapl\t"} {
+		data, err := service.GetStockData(context.Background(), Query{Symbol: symbol, Days: 1, Interval: client.IntervalDaily})
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", symbol, err)
+		}
+		if data.Symbol != "AAPL" {
+			t.Errorf("expected Symbol AAPL for %q, got %q", symbol, data.Symbol)
+		}
+	}
+
+	if len(provider.symbols) != 1 || provider.symbols[0] != "AAPL" {
+		t.Errorf("expected a single upstream call for AAPL, got %v", provider.symbols)
+	}
+	if items := store.Stats().Items; items != 1 {
+		t.Errorf("expected 1 cache entry, got %d", items)
+	}
+}
+
+// symbolRecordingProvider is a client.StockProvider recording the symbol of every call
+type symbolRecordingProvider struct {
+	symbols []string
+}
+
+func (p *symbolRecordingProvider) GetStockData(ctx context.Context, symbol string, days int, interval client.Interval) (*models.AlphaVantageResponse, error) {
+	p.symbols = append(p.symbols, symbol)
+	return &models.AlphaVantageResponse{
+		TimeSeries: map[string]models.DailyPrice{
+			"2023-01-03": {Open: "1", High: "1", Low: "1", Close: "1", Volume: "1"},
+		},
+	}, nil
+}
+
+func TestGetStockDataCoalescesConcurrentRequests(t *testing.T) {
+	release := make(chan struct{})
+	provider := &blockingProvider{release: release, err: errors.New("upstream unavailable")}
+	service := New(&config.Config{CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}, provider, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	query := Query{Symbol: "AAPL", Days: 7, Interval: client.IntervalDaily}
+
+	const callers = 10
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		go func() {
+			_, err := service.GetStockData(context.Background(), query)
+			errs <- err
+		}()
+	}
+
+	// Give the callers time to join the in-flight request before releasing it
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	for i := 0; i < callers; i++ {
+		if err := <-errs; err == nil || err.Error() != "upstream unavailable" {
+			t.Errorf("expected shared upstream error, got %v", err)
+		}
+	}
+
+	if calls := provider.calls.Load(); calls != 1 {
+		t.Errorf("expected 1 provider call, got %d", calls)
+	}
+}
+
+// blockingProvider is a client.StockProvider that blocks until release is closed
+type blockingProvider struct {
+	release chan struct{}
+	err     error
+	calls   atomic.Int32
+}
+
+func (p *blockingProvider) GetStockData(ctx context.Context, symbol string, days int, interval client.Interval) (*models.AlphaVantageResponse, error) {
+	p.calls.Add(1)
+	<-p.release
+	return nil, p.err
+}
+
+// stubProvider is a client.StockProvider returning a canned response
+type stubProvider struct {
+	response *models.AlphaVantageResponse
+	err      error
+	calls    int
+}
+
+func (p *stubProvider) GetStockData(ctx context.Context, symbol string, days int, interval client.Interval) (*models.AlphaVantageResponse, error) {
+	p.calls++
+	return p.response, p.err
+}
+
+// Helper function to compare floats with a small tolerance
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
 // Helper function to check if a string contains a substring
 func contains(s, substr string) bool {
 	return strings.Contains(s, substr)
 }
+
+func TestGetStockDataStoresResultInCache(t *testing.T) {
+	provider := &stubProvider{
+		response: &models.AlphaVantageResponse{
+			TimeSeries: map[string]models.DailyPrice{
+				"2023-01-03": {Open: "150.10", High: "150.10", Low: "150.10", Close: "150.10", Volume: "1000"},
+			},
+		},
+	}
+	store := &recordingStore{}
+	service := New(&config.Config{CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}, provider, store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	query := Query{Symbol: "AAPL", Days: 7, Interval: client.IntervalDaily}
+
+	data, err := service.GetStockData(context.Background(), query)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(store.sets) != 1 {
+		t.Fatalf("expected 1 cache write, got %d", len(store.sets))
+	}
+	set := store.sets[0]
+	if set.key != query.cacheKey() {
+		t.Errorf("expected key %q, got %q", query.cacheKey(), set.key)
+	}
+	if set.value != data {
+		t.Errorf("expected the returned data to be cached")
+	}
+	if set.duration != time.Minute {
+		t.Errorf("expected TTL of 1m, got %v", set.duration)
+	}
+}
+
+// recordingStore is a cache.Store that never hits and records every Set
+type recordingStore struct {
+	sets []recordedSet
+}
+
+type recordedSet struct {
+	key      string
+	value    interface{}
+	duration time.Duration
+}
+
+func (s *recordingStore) Get(key string) (interface{}, bool)    { return nil, false }
+func (s *recordingStore) GetItem(key string) (cache.Item, bool) { return cache.Item{}, false }
+func (s *recordingStore) Delete(key string)                     {}
+func (s *recordingStore) DeletePrefix(prefix string)            {}
+func (s *recordingStore) Cleanup()                              {}
+func (s *recordingStore) Stats() cache.Stats                    { return cache.Stats{} }
+
+func (s *recordingStore) Set(key string, value interface{}, duration time.Duration) {
+	s.sets = append(s.sets, recordedSet{key: key, value: value, duration: duration})
+}
+
+func TestGetStockDataTreatsUnexpectedCachedTypeAsMiss(t *testing.T) {
+	provider := &stubProvider{
+		response: &models.AlphaVantageResponse{
+			TimeSeries: map[string]models.DailyPrice{
+				"2023-01-03": {Open: "150.10", High: "150.10", Low: "150.10", Close: "150.10", Volume: "1000"},
+			},
+		},
+	}
+	store := cache.New(0)
+	service := New(&config.Config{CacheConfig: config.CacheConfig{CacheTTL: time.Minute}}, provider, store, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	query := Query{Symbol: "AAPL", Days: 7, Interval: client.IntervalDaily}
+	store.Set(query.cacheKey(), "not stock data", time.Minute)
+
+	data, err := service.GetStockData(context.Background(), query)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data.Symbol != "AAPL" {
+		t.Errorf("expected Symbol AAPL, got %s", data.Symbol)
+	}
+	if provider.calls != 1 {
+		t.Errorf("expected 1 provider call, got %d", provider.calls)
+	}
+}
+
+func TestGetMultipleStockDataBoundsConcurrency(t *testing.T) {
+	const concurrency = 2
+	provider := &concurrencyProvider{}
+	service := New(&config.Config{CacheConfig: config.CacheConfig{CacheTTL: time.Minute}, ClientConfig: config.ClientConfig{Concurrency: concurrency}}, provider, cache.New(0), slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	symbols := []string{"AAPL", "MSFT", "IBM", "GOOG", "AMZN", "META", "NFLX", "TSLA"}
+	results := service.GetMultipleStockData(context.Background(), symbols, Query{Days: 1, Interval: client.IntervalDaily})
+
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("%s: unexpected error: %v", result.Symbol, result.Err)
+		}
+	}
+	if got := provider.maxInFlight.Load(); got > concurrency {
+		t.Errorf("expected at most %d concurrent calls, got %d", concurrency, got)
+	}
+}
+
+// concurrencyProvider records the largest number of GetStockData calls in flight at once
+type concurrencyProvider struct {
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (p *concurrencyProvider) GetStockData(ctx context.Context, symbol string, days int, interval client.Interval) (*models.AlphaVantageResponse, error) {
+	current := p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+	for {
+		max := p.maxInFlight.Load()
+		if current <= max || p.maxInFlight.CompareAndSwap(max, current) {
+			break
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	return &models.AlphaVantageResponse{
+		TimeSeries: map[string]models.DailyPrice{
+			"2023-01-03": {Open: "1", High: "1", Low: "1", Close: "1", Volume: "1"},
+		},
+	}, nil
+}
@@ -0,0 +1,119 @@
+// Package tracing configures OpenTelemetry trace export
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/saedabdu/stockticker/internal/config"
+)
+
+// serviceName identifies this service in traces unless OTEL_SERVICE_NAME overrides it
+const serviceName = "stockticker"
+
+// urlAttribute is the span attribute in which otelhttp records outbound request URLs
+const urlAttribute = attribute.Key("http.url")
+
+// secretParams are the query parameters carrying provider credentials
+var secretParams = []string{"apikey", "token"}
+
+// Setup installs a global tracer provider that sends spans to the named exporter,
+// one of the config.TracingExporter values, and returns a function that flushes
+// and stops it. When exporter is empty tracing stays disabled: the global no-op
+// provider is left in place and the returned function does nothing.
+func Setup(ctx context.Context, exporter string) (func(context.Context) error, error) {
+	if exporter == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	spanExporter, err := newExporter(ctx, exporter)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK())
+	if err != nil {
+		return nil, fmt.Errorf("error creating trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(redactor{}),
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res))
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+// newExporter creates the span exporter with the given name
+func newExporter(ctx context.Context, exporter string) (sdktrace.SpanExporter, error) {
+	switch exporter {
+	case config.TracingExporterOTLP:
+		spanExporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error creating OTLP trace exporter: %w", err)
+		}
+		return spanExporter, nil
+	case config.TracingExporterStdout:
+		spanExporter, err := stdouttrace.New()
+		if err != nil {
+			return nil, fmt.Errorf("error creating stdout trace exporter: %w", err)
+		}
+		return spanExporter, nil
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q: must be %s or %s", exporter, config.TracingExporterOTLP, config.TracingExporterStdout)
+	}
+}
+
+// redactor is a span processor that masks provider credentials in recorded URLs
+// before the span can be exported
+type redactor struct{}
+
+// OnStart replaces the URL attribute of outbound requests with a redacted copy
+func (redactor) OnStart(_ context.Context, span sdktrace.ReadWriteSpan) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == urlAttribute {
+			span.SetAttributes(urlAttribute.String(redactURL(attr.Value.AsString())))
+		}
+	}
+}
+
+// OnEnd does nothing
+func (redactor) OnEnd(sdktrace.ReadOnlySpan) {}
+
+// Shutdown does nothing
+func (redactor) Shutdown(context.Context) error { return nil }
+
+// ForceFlush does nothing
+func (redactor) ForceFlush(context.Context) error { return nil }
+
+// redactURL returns rawURL with the values of any secret query parameters masked
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	query := u.Query()
+	for _, param := range secretParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,45 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRedactorMasksCredentials(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(redactor{}), sdktrace.WithSpanProcessor(recorder))
+
+	_, span := provider.Tracer("test").Start(context.Background(), "GET",
+		trace.WithAttributes(urlAttribute.String("https://www.alphavantage.co/query?apikey=secret&function=TIME_SERIES_DAILY&symbol=IBM")))
+	span.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	var got string
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == urlAttribute {
+			got = attr.Value.AsString()
+		}
+	}
+	want := "https://www.alphavantage.co/query?apikey=REDACTED&function=TIME_SERIES_DAILY&symbol=IBM"
+	if got != want {
+		t.Errorf("expected URL %s, got %s", want, got)
+	}
+}
+
+func TestSetupDisabled(t *testing.T) {
+	shutdown, err := Setup(context.Background(), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected shutdown error: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+// Package version reports which build of the service is running
+package version
+
+import "runtime/debug"
+
+// Build details, injected at build time with
+//
+//	go build -ldflags "-X github.com/saedabdu/stockticker/internal/version.Version=v1.2.3 ..."
+//
+// Commit and BuildTime fall back to the VCS details Go embeds in the binary
+// when they are not injected.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+func init() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if Commit == "" {
+				Commit = setting.Value
+			}
+		case "vcs.time":
+			if BuildTime == "" {
+				BuildTime = setting.Value
+			}
+		}
+	}
+}
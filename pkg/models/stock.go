@@ -1,9 +1,47 @@
 package models
 
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
 // AlphaVantageResponse represents the response from the AlphaVantage API
 type AlphaVantageResponse struct {
 	MetaData   MetaData              `json:"Meta Data"`
 	TimeSeries map[string]DailyPrice `json:"Time Series (Daily)"`
+
+	// Note and Information are returned instead of data when the API rejects a call,
+	// most commonly because the rate limit was exceeded
+	Note        string `json:"Note,omitempty"`
+	Information string `json:"Information,omitempty"`
+	// ErrorMessage is returned instead of data for an invalid call, such as an unknown symbol
+	ErrorMessage string `json:"Error Message,omitempty"`
+}
+
+// UnmarshalJSON decodes the response, accepting the time series under whichever
+// key the requested interval uses (e.g. "Weekly Time Series", "Time Series (5min)")
+func (r *AlphaVantageResponse) UnmarshalJSON(data []byte) error {
+	// alias avoids recursing into this method
+	type alias AlphaVantageResponse
+	if err := json.Unmarshal(data, (*alias)(r)); err != nil {
+		return err
+	}
+	if r.TimeSeries != nil {
+		return nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for key, value := range raw {
+		if strings.Contains(key, "Time Series") {
+			return json.Unmarshal(value, &r.TimeSeries)
+		}
+	}
+
+	return nil
 }
 
 // MetaData represents the metadata in the AlphaVantage API response
@@ -15,24 +53,198 @@ type MetaData struct {
 	TimeZone      string `json:"5. Time Zone"`
 }
 
-// DailyPrice represents a daily price entry in the AlphaVantage API response
+// UnmarshalJSON decodes the metadata by field name, since the numbering of the
+// keys differs between functions (e.g. "4. Time Zone" for weekly series and
+// "6. Time Zone" for intraday series)
+func (m *MetaData) UnmarshalJSON(data []byte) error {
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	for key, value := range raw {
+		// Strip the "N. " prefix
+		_, name, found := strings.Cut(key, ". ")
+		if !found {
+			name = key
+		}
+
+		switch name {
+		case "Information":
+			m.Information = value
+		case "Symbol":
+			m.Symbol = value
+		case "Last Refreshed":
+			m.LastRefreshed = value
+		case "Output Size":
+			m.OutputSize = value
+		case "Time Zone":
+			m.TimeZone = value
+		}
+	}
+
+	return nil
+}
+
+// DailyPrice represents a price entry in the AlphaVantage API response.
+// The same shape is used for daily, weekly, monthly and intraday series.
 type DailyPrice struct {
 	Open   string `json:"1. open"`
 	High   string `json:"2. high"`
 	Low    string `json:"3. low"`
 	Close  string `json:"4. close"`
 	Volume string `json:"5. volume"`
+
+	// AdjustedClose accounts for splits and dividends; only adjusted series report it
+	AdjustedClose string `json:"5. adjusted close,omitempty"`
 }
 
-// StockPrice represents a simplified stock price entry
+// UnmarshalJSON decodes the entry by field name, since adjusted series insert
+// "5. adjusted close" and renumber the volume as "6. volume"
+func (p *DailyPrice) UnmarshalJSON(data []byte) error {
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	for key, value := range raw {
+		// Strip the "N. " prefix
+		_, name, found := strings.Cut(key, ". ")
+		if !found {
+			name = key
+		}
+
+		switch name {
+		case "open":
+			p.Open = value
+		case "high":
+			p.High = value
+		case "low":
+			p.Low = value
+		case "close":
+			p.Close = value
+		case "adjusted close":
+			p.AdjustedClose = value
+		case "volume":
+			p.Volume = value
+		}
+	}
+
+	return nil
+}
+
+// StockPrice represents a stock price entry. Date holds a date such as "2023-01-03",
+// or a timestamp such as "2023-01-03 16:00:00" for intraday series.
 type StockPrice struct {
-	Date  string  `json:"date"`
-	Close float64 `json:"close"`
+	Date   string  `json:"date" xml:"date"`
+	Open   float64 `json:"open" xml:"open"`
+	High   float64 `json:"high" xml:"high"`
+	Low    float64 `json:"low" xml:"low"`
+	Close  float64 `json:"close" xml:"close"`
+	Volume int64   `json:"volume" xml:"volume"`
+}
+
+// PriceField names the price of each entry that statistics are computed from
+type PriceField string
+
+// Supported price fields
+const (
+	PriceFieldOpen  PriceField = "open"
+	PriceFieldHigh  PriceField = "high"
+	PriceFieldLow   PriceField = "low"
+	PriceFieldClose PriceField = "close"
+)
+
+// Value returns the price named by field, or the close when field is empty or unknown
+func (p StockPrice) Value(field PriceField) float64 {
+	switch field {
+	case PriceFieldOpen:
+		return p.Open
+	case PriceFieldHigh:
+		return p.High
+	case PriceFieldLow:
+		return p.Low
+	default:
+		return p.Close
+	}
 }
 
-// StockData represents processed stock data with prices and average
+// StockData represents processed stock data with prices, average and summary statistics
 type StockData struct {
 	Symbol  string       `json:"symbol"`
 	Prices  []StockPrice `json:"prices"`
 	Average float64      `json:"average"`
+	Median  float64      `json:"median"`
+	Min     float64      `json:"min"`
+	Max     float64      `json:"max"`
+	StdDev  float64      `json:"std_dev"`
+
+	PercentChange float64 `json:"percent_change"`
+	Currency      string  `json:"currency"`
+	LastRefreshed string  `json:"last_refreshed"`
+	TimeZone      string  `json:"time_zone"`
+	// RequestedDays is unset for date range queries; ReturnedDays is smaller when history is short
+	RequestedDays int `json:"requested_days,omitempty"`
+	ReturnedDays  int `json:"returned_days"`
+
+	// PriceField is the price the statistics and derived series are computed from;
+	// empty means the close
+	PriceField PriceField `json:"price_field,omitempty"`
+
+	// SMA is the simple moving average of the PriceField prices, when requested
+	SMA []SeriesPoint `json:"sma,omitempty"`
+	// EMA is the exponential moving average of the PriceField prices, when requested
+	EMA []SeriesPoint `json:"ema,omitempty"`
+	// Returns is the day-over-day percentage change of the PriceField prices, when requested
+	Returns []SeriesPoint `json:"returns,omitempty"`
+
+	// VWAP is the volume-weighted average price over the window, when requested
+	// and the window has volume
+	VWAP *float64 `json:"vwap,omitempty"`
+
+	// Stale is set when the provider last refreshed the data longer ago than the
+	// configured maximum staleness
+	Stale bool `json:"stale,omitempty"`
+
+	// Page describes which part of the window Prices holds, when paginated
+	Page *Page `json:"page,omitempty"`
+
+	// CachedAt is when the data was fetched from the provider and cached
+	CachedAt time.Time `json:"-"`
+	// ServedStale is set when the data expired from the cache but was returned
+	// because fetching fresh data failed
+	ServedStale bool `json:"-"`
+}
+
+// SeriesPoint is the value of a derived series, such as a moving average, on a date
+type SeriesPoint struct {
+	Date  string  `json:"date" xml:"date"`
+	Value float64 `json:"value" xml:"value"`
+}
+
+// Page locates a page of prices within the full window. NextOffset is the
+// offset of the following page, or nil on the last page.
+type Page struct {
+	Offset     int  `json:"offset" xml:"offset"`
+	Limit      int  `json:"limit,omitempty" xml:"limit,omitempty"`
+	Total      int  `json:"total" xml:"total"`
+	NextOffset *int `json:"next_offset,omitempty" xml:"next_offset,omitempty"`
+}
+
+// Quote is the latest trade of a symbol. Change and ChangePercent are relative
+// to the previous close.
+type Quote struct {
+	Symbol           string  `json:"symbol"`
+	Price            float64 `json:"price"`
+	Change           float64 `json:"change"`
+	ChangePercent    float64 `json:"change_percent"`
+	LatestTradingDay string  `json:"latest_trading_day"`
+}
+
+// SymbolMatch is a company whose symbol or name matches a search
+type SymbolMatch struct {
+	Symbol   string `json:"symbol"`
+	Name     string `json:"name"`
+	Region   string `json:"region"`
+	Currency string `json:"currency"`
 }